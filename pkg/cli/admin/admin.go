@@ -22,6 +22,7 @@ import (
 	"github.com/openshift/oc/pkg/cli/admin/createlogintemplate"
 	"github.com/openshift/oc/pkg/cli/admin/createproviderselectiontemplate"
 	"github.com/openshift/oc/pkg/cli/admin/groups"
+	"github.com/openshift/oc/pkg/cli/admin/imagemirrors"
 	"github.com/openshift/oc/pkg/cli/admin/inspect"
 	"github.com/openshift/oc/pkg/cli/admin/migrate"
 	migrateetcd "github.com/openshift/oc/pkg/cli/admin/migrate/etcd"
@@ -112,6 +113,7 @@ func NewCommandAdmin(f kcmdutil.Factory, streams genericclioptions.IOStreams) *c
 				createlogintemplate.NewCommandCreateLoginTemplate(f, streams),
 				createproviderselectiontemplate.NewCommandCreateProviderSelectionTemplate(f, streams),
 				createerrortemplate.NewCommandCreateErrorTemplate(f, streams),
+				imagemirrors.NewCmdImageMirrors(f, streams),
 			},
 		},
 	}
@@ -0,0 +1,113 @@
+package imagemirrors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	operatorclient "github.com/openshift/client-go/operator/clientset/versioned/typed/operator/v1alpha1"
+	"github.com/openshift/oc/pkg/cli/image/strategy"
+)
+
+var (
+	driftLong = templates.LongDesc(`
+		Compare the ImageContentSourcePolicies of the cluster with a file
+
+		Reports every source entry that the cluster declares but the file does not (Added),
+		that the file declares but the cluster does not (Removed), and that both declare with
+		different mirrors or mirror order (Changed). Entries are matched by policy name and
+		source.
+	`)
+
+	driftExample = templates.Examples(`
+		# Report how the policies of the current cluster differ from those in a file
+		oc adm image-mirrors drift --icsp-file=icsp.yaml
+	`)
+)
+
+type DriftOptions struct {
+	genericclioptions.IOStreams
+
+	ICSPFile string
+
+	Strategy *strategy.OnErrorStrategy
+}
+
+func NewDriftOptions(streams genericclioptions.IOStreams) *DriftOptions {
+	return &DriftOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdDrift reports how the policies of the cluster differ from those of a file.
+func NewCmdDrift(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewDriftOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "drift --icsp-file=FILE",
+		Short:   "Compare the ImageContentSourcePolicies of the cluster with a file",
+		Long:    driftLong,
+		Example: driftExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(f, cmd, args))
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	cmd.Flags().StringVar(&o.ICSPFile, "icsp-file", o.ICSPFile, "Path or http(s) URL of the ImageContentSourcePolicy file to compare the cluster with.")
+	return cmd
+}
+
+func (o *DriftOptions) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 0 {
+		return kcmdutil.UsageErrorf(cmd, "drift expects no arguments")
+	}
+	if len(o.ICSPFile) == 0 {
+		return kcmdutil.UsageErrorf(cmd, "--icsp-file is required")
+	}
+	config, err := f.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+	operatorClient, err := operatorclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	o.Strategy = strategy.NewICSPOnErrorStrategy(operatorClient.ImageContentSourcePolicies(), o.ICSPFile, strategy.WithWarnings(o.ErrOut))
+	return nil
+}
+
+func (o *DriftOptions) Run() error {
+	drift, err := o.Strategy.Drift(context.TODO())
+	if err != nil {
+		return err
+	}
+	return printDrift(o.Out, drift)
+}
+
+// printDrift writes a table of drift to out, or a single line if there is none.
+func printDrift(out io.Writer, drift []strategy.PolicyDrift) error {
+	if len(drift) == 0 {
+		_, err := fmt.Fprintln(out, "The cluster matches the file")
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "TYPE\tPOLICY\tSOURCE\tFILE\tCLUSTER\n")
+	for _, d := range drift {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Type, d.Policy, d.Source, mirrorsOrNone(d.Expected), mirrorsOrNone(d.Actual))
+	}
+	return w.Flush()
+}
+
+func mirrorsOrNone(mirrors []string) string {
+	if len(mirrors) == 0 {
+		return "-"
+	}
+	return strings.Join(mirrors, ",")
+}
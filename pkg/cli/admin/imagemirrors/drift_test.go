@@ -0,0 +1,51 @@
+package imagemirrors
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/openshift/oc/pkg/cli/image/strategy"
+)
+
+func TestPrintDrift(t *testing.T) {
+	tests := []struct {
+		name     string
+		drift    []strategy.PolicyDrift
+		expected string
+	}{
+		{
+			name: "drift",
+			drift: []strategy.PolicyDrift{
+				{Type: strategy.DriftAdded, Policy: "added", Source: "quay.io/ocp/installer", Actual: []string{"first.example.com/ocp/installer"}},
+				{
+					Type:     strategy.DriftChanged,
+					Policy:   "release",
+					Source:   "quay.io/ocp/release",
+					Expected: []string{"first.example.com/ocp/release", "second.example.com/ocp/release"},
+					Actual:   []string{"second.example.com/ocp/release"},
+				},
+				{Type: strategy.DriftRemoved, Policy: "release", Source: "quay.io/ocp/tools", Expected: []string{"first.example.com/ocp/tools"}},
+			},
+			expected: `TYPE     POLICY   SOURCE                 FILE                                                          CLUSTER
+Added    added    quay.io/ocp/installer  -                                                             first.example.com/ocp/installer
+Changed  release  quay.io/ocp/release    first.example.com/ocp/release,second.example.com/ocp/release  second.example.com/ocp/release
+Removed  release  quay.io/ocp/tools      first.example.com/ocp/tools                                   -
+`,
+		},
+		{
+			name:     "no drift",
+			expected: "The cluster matches the file\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := printDrift(buf, tt.drift); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("unexpected output:\n%s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package imagemirrors
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var imageMirrorsLong = templates.LongDesc(`
+	Manage the image mirrors of a cluster
+
+	These commands check the ImageContentSourcePolicies of a cluster against the
+	policies they are expected to match.`)
+
+// NewCmdImageMirrors exposes commands for managing the mirrors of a cluster.
+func NewCmdImageMirrors(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "image-mirrors COMMAND",
+		Short: "Manage the image mirrors of a cluster",
+		Long:  imageMirrorsLong,
+		Run:   kcmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+	cmd.AddCommand(NewCmdDrift(f, streams))
+	return cmd
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/openshift/oc/pkg/cli/image/extract"
 	"github.com/openshift/oc/pkg/cli/image/info"
 	"github.com/openshift/oc/pkg/cli/image/mirror"
+	"github.com/openshift/oc/pkg/cli/image/mirrors"
 	"github.com/openshift/oc/pkg/cli/image/serve"
 	cmdutil "github.com/openshift/oc/pkg/helpers/cmd"
 )
@@ -42,6 +43,7 @@ func NewCmdImage(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra
 		{
 			Message: "Advanced commands:",
 			Commands: []*cobra.Command{
+				mirrors.NewCmdMirrors(f, streams),
 				serve.NewServe(streams),
 				append.NewCmdAppendImage(streams),
 				extract.NewExtract(streams),
@@ -0,0 +1,183 @@
+package mirrors
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	operatorclient "github.com/openshift/client-go/operator/clientset/versioned/typed/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/oc/pkg/cli/image/strategy"
+)
+
+var (
+	explainLong = templates.LongDesc(`
+		Explain the mirrors of an image
+
+		Shows every location the image may be retrieved from, the ImageContentSourcePolicy
+		that declared it, and the mirrors of matching sources that were skipped and why.
+		Policies are read from the cluster unless --icsp-file is set, in which case
+		--icsp-merge also reads those of the cluster, after the policies of the file.
+	`)
+
+	explainExample = templates.Examples(`
+		# Explain the mirrors of an image using the policies of the current cluster
+		oc image mirrors explain quay.io/openshift-release-dev/ocp-release:4.8.0-x86_64
+
+		# Explain the mirrors of an image using the policies in a file
+		oc image mirrors explain --icsp-file=icsp.yaml quay.io/openshift-release-dev/ocp-release:4.8.0-x86_64
+
+		# Explain the mirrors of an image using the policies in a file and those of the cluster
+		oc image mirrors explain --icsp-file=icsp.yaml --icsp-merge quay.io/openshift-release-dev/ocp-release:4.8.0-x86_64
+
+		# Explain the mirrors of an image and write the resolution metrics to a file
+		oc image mirrors explain --metrics-file=metrics.prom quay.io/openshift-release-dev/ocp-release:4.8.0-x86_64
+	`)
+)
+
+type ExplainOptions struct {
+	genericclioptions.IOStreams
+
+	ICSPFile    string
+	ICSPMerge   bool
+	MetricsFile string
+	Image       reference.DockerImageReference
+
+	Strategy *strategy.OnErrorStrategy
+}
+
+func NewExplainOptions(streams genericclioptions.IOStreams) *ExplainOptions {
+	return &ExplainOptions{
+		IOStreams: streams,
+	}
+}
+
+// NewCmdExplain describes how the mirrors of an image are resolved.
+func NewCmdExplain(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewExplainOptions(streams)
+	cmd := &cobra.Command{
+		Use:     "explain IMAGE",
+		Short:   "Explain the mirrors of an image",
+		Long:    explainLong,
+		Example: explainExample,
+		Run: func(cmd *cobra.Command, args []string) {
+			kcmdutil.CheckErr(o.Complete(f, cmd, args))
+			kcmdutil.CheckErr(o.Run())
+		},
+	}
+	o.Bind(cmd.Flags())
+	return cmd
+}
+
+// Bind adds the flags selecting the policies, and where to write the resolution metrics,
+// to flags.
+func (o *ExplainOptions) Bind(flags *pflag.FlagSet) {
+	flags.StringVar(&o.ICSPFile, "icsp-file", o.ICSPFile, "Path or http(s) URL of an ImageContentSourcePolicy file. If set, the policies of the cluster are not used unless --icsp-merge is set.")
+	flags.BoolVar(&o.ICSPMerge, "icsp-merge", o.ICSPMerge, "If true, use the policies of the cluster in addition to those of --icsp-file, whose mirrors are tried first.")
+	flags.StringVar(&o.MetricsFile, "metrics-file", o.MetricsFile, "If set, write the resolution metrics to this file in the Prometheus text format once the image is explained.")
+}
+
+func (o *ExplainOptions) Complete(f kcmdutil.Factory, cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return kcmdutil.UsageErrorf(cmd, "explain expects one argument, an image pull spec")
+	}
+	ref, err := strategy.ParseReference(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid image %q: %v", args[0], err)
+	}
+	o.Image = ref
+	if o.ICSPMerge && len(o.ICSPFile) == 0 {
+		return kcmdutil.UsageErrorf(cmd, "--icsp-merge requires --icsp-file")
+	}
+
+	var client strategy.ICSPLister
+	if len(o.ICSPFile) == 0 || o.ICSPMerge {
+		config, err := f.ToRESTConfig()
+		if err != nil {
+			return err
+		}
+		operatorClient, err := operatorclient.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		client = operatorClient.ImageContentSourcePolicies()
+	}
+	o.Strategy = o.newStrategy(client)
+	return nil
+}
+
+// newStrategy returns the strategy resolving against the policies selected by the flags,
+// listing those of the cluster with client.
+func (o *ExplainOptions) newStrategy(client strategy.ICSPLister) *strategy.OnErrorStrategy {
+	opts := []strategy.Option{strategy.WithWarnings(o.ErrOut)}
+	if o.ICSPMerge {
+		opts = append(opts, strategy.WithClusterMerge())
+	}
+	return strategy.NewICSPOnErrorStrategy(client, o.ICSPFile, opts...)
+}
+
+func (o *ExplainOptions) Run() error {
+	explanation, err := o.Strategy.Explain(context.TODO(), o.Image)
+	if err != nil {
+		return err
+	}
+	if err := printExplanation(o.Out, explanation); err != nil {
+		return err
+	}
+	if len(o.MetricsFile) > 0 {
+		return o.Strategy.WriteMetricsFile(o.MetricsFile)
+	}
+	return nil
+}
+
+// printExplanation writes a human readable description of explanation to out.
+func printExplanation(out io.Writer, explanation *strategy.Explanation) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Image:\t%s\n", explanation.Image.Exact())
+	if len(explanation.Policies) == 0 {
+		fmt.Fprintf(w, "Policies:\t<none>\n")
+	}
+	for i, policy := range explanation.Policies {
+		label := ""
+		if i == 0 {
+			label = "Policies:"
+		}
+		fmt.Fprintf(w, "%s\t%s (generation %d)\n", label, policy.Name, policy.Generation)
+	}
+	if len(explanation.Reason) > 0 {
+		fmt.Fprintf(w, "Reason:\t%s\n", explanation.Reason)
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintf(w, "ALTERNATE\tPOLICY\tSOURCE\tSTATUS\n")
+	for _, alternate := range explanation.Alternates {
+		status := "selected"
+		if len(alternate.Policy) == 0 {
+			status = "requested"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", alternate.Ref.Exact(), valueOrNone(alternate.Policy), valueOrNone(alternate.Source), status)
+	}
+	for _, skipped := range explanation.Skipped {
+		ref := skipped.Ref.Exact()
+		if len(ref) == 0 {
+			ref = "<all mirrors>"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\tskipped: %s\n", ref, valueOrNone(skipped.Policy), valueOrNone(skipped.Source), skipped.Reason)
+	}
+	return w.Flush()
+}
+
+func valueOrNone(value string) string {
+	if len(value) == 0 {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,209 @@
+package mirrors
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/oc/pkg/cli/image/strategy"
+)
+
+func mustParse(t *testing.T, image string) reference.DockerImageReference {
+	ref, err := reference.Parse(image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestPrintExplanation(t *testing.T) {
+	tests := []struct {
+		name        string
+		explanation *strategy.Explanation
+		expected    string
+	}{
+		{
+			name: "mirrors",
+			explanation: &strategy.Explanation{
+				Image: mustParse(t, "quay.io/ocp/release:4.8"),
+				Alternates: []strategy.Alternate{
+					{Ref: mustParse(t, "quay.io/ocp/release:4.8")},
+					{Ref: mustParse(t, "registry.example.com/ocp/release:4.8"), Policy: "release", Source: "quay.io/ocp/release"},
+				},
+				Policies: []strategy.MatchedPolicy{{Name: "release", Generation: 3}, {Name: "extra", Generation: 1}},
+				Skipped: []strategy.Skipped{
+					{Ref: mustParse(t, "public.example.org/ocp/release:4.8"), Policy: "release", Source: "quay.io/ocp/release", Reason: strategy.SkipFiltered},
+					{Policy: "extra", Source: "quay.io/ocp", Reason: strategy.SkipTagCondition},
+				},
+			},
+			expected: `Image:     quay.io/ocp/release:4.8
+Policies:  release (generation 3)
+           extra (generation 1)
+
+ALTERNATE                             POLICY   SOURCE               STATUS
+quay.io/ocp/release:4.8               -        -                    requested
+registry.example.com/ocp/release:4.8  release  quay.io/ocp/release  selected
+public.example.org/ocp/release:4.8    release  quay.io/ocp/release  skipped: Filtered
+<all mirrors>                         extra    quay.io/ocp          skipped: TagConditionNotMet
+`,
+		},
+		{
+			name: "no mirrors",
+			explanation: &strategy.Explanation{
+				Image:      mustParse(t, "docker.io/library/busybox:latest"),
+				Alternates: []strategy.Alternate{{Ref: mustParse(t, "docker.io/library/busybox:latest")}},
+				Reason:     strategy.NoSourceMatched,
+			},
+			expected: `Image:     docker.io/library/busybox:latest
+Policies:  <none>
+Reason:    NoSourceMatched
+
+ALTERNATE                         POLICY  SOURCE  STATUS
+docker.io/library/busybox:latest  -       -       requested
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := printExplanation(buf, tt.explanation); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("unexpected output:\n%s", buf.String())
+			}
+		})
+	}
+}
+
+type fakeICSPLister struct {
+	items []operatorv1alpha1.ImageContentSourcePolicy
+}
+
+func (f *fakeICSPLister) List(ctx context.Context, opts metav1.ListOptions) (*operatorv1alpha1.ImageContentSourcePolicyList, error) {
+	return &operatorv1alpha1.ImageContentSourcePolicyList{Items: f.items}, nil
+}
+
+func TestExplainICSPMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "icsp.yaml")
+	policy := `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: file
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - file.example.com/ocp/release
+`
+	if err := ioutil.WriteFile(path, []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec: operatorv1alpha1.ImageContentSourcePolicySpec{
+			RepositoryDigestMirrors: []operatorv1alpha1.RepositoryDigestMirrors{
+				{Source: "quay.io/ocp/release", Mirrors: []string{"cluster.example.com/ocp/release"}},
+			},
+		},
+	}}}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "file only",
+			args:     []string{"--icsp-file=" + path},
+			expected: []string{"quay.io/ocp/release:4.8", "file.example.com/ocp/release:4.8"},
+		},
+		{
+			name:     "merged",
+			args:     []string{"--icsp-file=" + path, "--icsp-merge"},
+			expected: []string{"quay.io/ocp/release:4.8", "file.example.com/ocp/release:4.8", "cluster.example.com/ocp/release:4.8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewExplainOptions(genericclioptions.NewTestIOStreamsDiscard())
+			flags := pflag.NewFlagSet("explain", pflag.ContinueOnError)
+			o.Bind(flags)
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			alternates, err := o.newStrategy(client).OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var actual []string
+			for _, alternate := range alternates {
+				actual = append(actual, alternate.Exact())
+			}
+			if !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExplainCompleteIPv6(t *testing.T) {
+	o := NewExplainOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.ICSPFile = filepath.Join(t.TempDir(), "icsp.yaml")
+	if err := o.Complete(nil, NewCmdExplain(nil, genericclioptions.NewTestIOStreamsDiscard()), []string{"[fd00::1]:5000/ocp/release:4.8"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o.Image.Registry != "[fd00::1]:5000" || o.Image.Namespace != "ocp" || o.Image.Name != "release" || o.Image.Tag != "4.8" {
+		t.Errorf("unexpected image %#v", o.Image)
+	}
+}
+
+func TestExplainMetricsFile(t *testing.T) {
+	dir := t.TempDir()
+	policy := filepath.Join(dir, "icsp.yaml")
+	if err := ioutil.WriteFile(policy, []byte(`apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: file
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - file.example.com/ocp/release
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	o := NewExplainOptions(genericclioptions.NewTestIOStreamsDiscard())
+	o.ICSPFile = policy
+	o.MetricsFile = filepath.Join(dir, "metrics.prom")
+	if err := o.Complete(nil, NewCmdExplain(nil, genericclioptions.NewTestIOStreamsDiscard()), []string{"quay.io/ocp/release:4.8"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := o.Run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(o.MetricsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"oc_image_mirror_images_resolved_total 1\n",
+		"oc_image_mirror_mirrors_offered_total 1\n",
+		"oc_image_mirror_policies_matched_total 1\n",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("expected the metrics to contain %q, got:\n%s", expected, data)
+		}
+	}
+}
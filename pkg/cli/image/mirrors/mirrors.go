@@ -0,0 +1,26 @@
+package mirrors
+
+import (
+	"github.com/spf13/cobra"
+
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kcmdutil "k8s.io/kubectl/pkg/cmd/util"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+// NewCmdMirrors exposes commands for inspecting the mirrors of images.
+func NewCmdMirrors(f kcmdutil.Factory, streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mirrors COMMAND",
+		Short: "Inspect the mirrors configured for images",
+		Long: templates.LongDesc(`
+			Inspect the mirrors configured for images
+
+			These commands show how ImageContentSourcePolicies on a cluster or in a file
+			map images to the mirrors they may be retrieved from.
+		`),
+		Run: kcmdutil.DefaultSubCommandRun(streams.ErrOut),
+	}
+	cmd.AddCommand(NewCmdExplain(f, streams))
+	return cmd
+}
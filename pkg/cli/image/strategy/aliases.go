@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"strings"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// WithHostAliases treats each registry host in aliases as another name for the host it maps
+// to, e.g. a renamed registry mapped to its new name. Aliased hosts are replaced in the
+// sources and mirrors of every policy and in requested images before they are matched,
+// so images and policies may use either name. Requested images are still returned as
+// given. Hosts are compared case-insensitively and include any port.
+func WithHostAliases(aliases map[string]string) Option {
+	return func(s *OnErrorStrategy) {
+		if s.hostAliases == nil {
+			s.hostAliases = make(map[string]string, len(aliases))
+		}
+		for alias, host := range aliases {
+			s.hostAliases[strings.ToLower(alias)] = strings.ToLower(host)
+		}
+	}
+}
+
+// aliasRepository replaces the host of repository if it is an alias.
+func (s *OnErrorStrategy) aliasRepository(repository string) string {
+	host, path := repository, ""
+	if i := strings.Index(repository, "/"); i >= 0 {
+		host, path = repository[:i], repository[i:]
+	}
+	if canonical, ok := s.hostAliases[strings.ToLower(host)]; ok {
+		return canonical + path
+	}
+	return repository
+}
+
+// aliasRef replaces the registry of ref if it is an alias.
+func (s *OnErrorStrategy) aliasRef(ref reference.DockerImageReference) reference.DockerImageReference {
+	if canonical, ok := s.hostAliases[strings.ToLower(ref.Registry)]; ok {
+		ref.Registry = canonical
+	}
+	return ref
+}
+
+// aliasPolicies returns icspList with aliased hosts replaced in every source and mirror.
+// The objects in icspList are not modified.
+func (s *OnErrorStrategy) aliasPolicies(icspList []operatorv1alpha1.ImageContentSourcePolicy) []operatorv1alpha1.ImageContentSourcePolicy {
+	aliased := make([]operatorv1alpha1.ImageContentSourcePolicy, 0, len(icspList))
+	for i := range icspList {
+		icsp := icspList[i].DeepCopy()
+		for j := range icsp.Spec.RepositoryDigestMirrors {
+			rdm := &icsp.Spec.RepositoryDigestMirrors[j]
+			rdm.Source = s.aliasRepository(normalizeRepository(rdm.Source))
+			for k, mirror := range rdm.Mirrors {
+				if strings.TrimSpace(mirror) == NeverMirror {
+					continue
+				}
+				rdm.Mirrors[k] = s.aliasRepository(normalizeRepository(mirror))
+			}
+		}
+		aliased = append(aliased, *icsp)
+	}
+	return aliased
+}
@@ -0,0 +1,63 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithHostAliases(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("new-registry.example.com/ocp/release", "old-mirror.example.com:5000/ocp/release"),
+		),
+		newICSP("legacy",
+			rdm("old-registry.example.com/ocp/release", "legacy.example.com/ocp/release"),
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "", WithHostAliases(map[string]string{
+		"Old-Registry.example.com":    "new-registry.example.com",
+		"old-mirror.example.com:5000": "mirror.example.com",
+	}))
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "old-registry.example.com/ocp/release:4.8",
+			expected: []string{
+				"old-registry.example.com/ocp/release:4.8",
+				"mirror.example.com/ocp/release:4.8",
+				"legacy.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			image: "new-registry.example.com/ocp/release:4.8",
+			expected: []string{
+				"new-registry.example.com/ocp/release:4.8",
+				"mirror.example.com/ocp/release:4.8",
+				"legacy.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			image:    "old-registry.example.com:5000/ocp/release:4.8",
+			expected: []string{"old-registry.example.com:5000/ocp/release:4.8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+	if client.items[1].Spec.RepositoryDigestMirrors[0].Source != "old-registry.example.com/ocp/release" {
+		t.Errorf("expected the listed policies not to be modified")
+	}
+}
@@ -0,0 +1,132 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+const (
+	// TagPatternsAnnotation restricts the mirrors of individual sources in a policy to inputs
+	// whose tag matches one of a set of glob patterns. The value is a JSON object mapping a
+	// source to its patterns, e.g. {"quay.io/ocp/release": ["4.*"]}. Inputs without a tag
+	// never satisfy a condition, and sources absent from the object are unconditional.
+	TagPatternsAnnotation = "mirror.openshift.io/tag-patterns"
+
+	// MirrorGroupsAnnotation marks mirror registry hosts that serve the same content, for
+	// instance several hostnames in front of one content-addressable store. The value is a
+	// JSON object mapping a group name to its hosts, e.g. {"cache": ["a.example.com",
+	// "b.example.com"]}. Mirrors in a group that differ only by host are collapsed to the
+	// first one in policy order. Groups apply to every policy being resolved.
+	MirrorGroupsAnnotation = "mirror.openshift.io/mirror-groups"
+
+	// RedirectsAnnotation rewrites images under a source to a new canonical source, whose
+	// mirrors are then used instead, for repositories that have moved. The value is a JSON
+	// object mapping the old source to the new one, e.g. {"quay.io/old/app":
+	// "quay.io/new/app"}. The redirected location is returned right after the requested
+	// image, and the most specific redirect across all policies applies.
+	RedirectsAnnotation = "mirror.openshift.io/redirects"
+)
+
+// tagPatterns returns the tag conditions declared on icsp, keyed by normalized source.
+func tagPatterns(icsp *operatorv1alpha1.ImageContentSourcePolicy) (map[string][]string, error) {
+	value, ok := icsp.Annotations[TagPatternsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var conditions map[string][]string
+	if err := json.Unmarshal([]byte(value), &conditions); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on ImageContentSourcePolicy %s: %v", TagPatternsAnnotation, icsp.Name, err)
+	}
+	normalized := make(map[string][]string, len(conditions))
+	for source, patterns := range conditions {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid tag pattern %q for source %q on ImageContentSourcePolicy %s: %v", pattern, source, icsp.Name, err)
+			}
+		}
+		source = normalizeRepository(source)
+		normalized[source] = append(normalized[source], patterns...)
+	}
+	return normalized, nil
+}
+
+// tagMatches returns true if tag satisfies any of patterns. A source without patterns
+// applies to every input.
+func tagMatches(tag string, patterns []string) bool {
+	if patterns == nil {
+		return true
+	}
+	if len(tag) == 0 {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, tag); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// mirrorGroups returns the group each mirror host was assigned to across icspList.
+func mirrorGroups(icspList []operatorv1alpha1.ImageContentSourcePolicy) (map[string]string, error) {
+	groups := make(map[string]string)
+	for i := range icspList {
+		icsp := &icspList[i]
+		value, ok := icsp.Annotations[MirrorGroupsAnnotation]
+		if !ok {
+			continue
+		}
+		var declared map[string][]string
+		if err := json.Unmarshal([]byte(value), &declared); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation on ImageContentSourcePolicy %s: %v", MirrorGroupsAnnotation, icsp.Name, err)
+		}
+		for group, hosts := range declared {
+			for _, host := range hosts {
+				if existing, ok := groups[host]; ok && existing != group {
+					return nil, fmt.Errorf("mirror host %s is assigned to both group %q and %q", host, existing, group)
+				}
+				groups[host] = group
+			}
+		}
+	}
+	return groups, nil
+}
+
+// equivalenceKey returns the identity of ref for removing duplicates, treating mirrors on
+// hosts of the same group as the same location.
+func equivalenceKey(ref reference.DockerImageReference, groups map[string]string) reference.DockerImageReference {
+	if group, ok := groups[ref.Registry]; ok {
+		ref.Registry = "group:" + group
+	}
+	return ref
+}
+
+// redirect is a source rewritten to a new canonical source before its mirrors are matched.
+type redirect struct {
+	policy string
+	source string
+	target string
+	// suffix is the remainder of the repository below source.
+	suffix string
+}
+
+// redirects returns the source redirects declared on icsp, keyed by normalized source.
+func redirects(icsp *operatorv1alpha1.ImageContentSourcePolicy) (map[string]string, error) {
+	value, ok := icsp.Annotations[RedirectsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var declared map[string]string
+	if err := json.Unmarshal([]byte(value), &declared); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on ImageContentSourcePolicy %s: %v", RedirectsAnnotation, icsp.Name, err)
+	}
+	normalized := make(map[string]string, len(declared))
+	for source, target := range declared {
+		normalized[normalizeRepository(source)] = normalizeRepository(target)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,174 @@
+package strategy
+
+import (
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func withAnnotations(icsp operatorv1alpha1.ImageContentSourcePolicy, annotations map[string]string) operatorv1alpha1.ImageContentSourcePolicy {
+	icsp.Annotations = annotations
+	return icsp
+}
+
+func TestTagPatterns(t *testing.T) {
+	icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+		withAnnotations(
+			newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+			map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/release/": ["4.*"]}`},
+		),
+		newICSP("other", rdm("quay.io/ocp/release", "other.example.com/ocp/release")),
+	}
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "quay.io/ocp/release:4.5",
+			expected: []string{
+				"quay.io/ocp/release:4.5",
+				"registry.example.com/ocp/release:4.5",
+				"other.example.com/ocp/release:4.5",
+			},
+		},
+		{
+			image: "quay.io/ocp/release:latest",
+			expected: []string{
+				"quay.io/ocp/release:latest",
+				"other.example.com/ocp/release:latest",
+			},
+		},
+		{
+			image: "quay.io/ocp/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"other.example.com/ocp/release@" + testDigest,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := alternativeImageSources(mustParse(t, tt.image), icspList)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTagPatternsUnconditionalWithoutAnnotation(t *testing.T) {
+	icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}
+	alternates, err := alternativeImageSources(mustParse(t, "quay.io/ocp/release:latest"), icspList)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"quay.io/ocp/release:latest", "registry.example.com/ocp/release:latest"}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestTagPatternsInvalid(t *testing.T) {
+	for _, value := range []string{`not json`, `{"quay.io/ocp/release": ["4.["]}`} {
+		icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+			withAnnotations(
+				newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+				map[string]string{TagPatternsAnnotation: value},
+			),
+		}
+		if _, err := alternativeImageSources(mustParse(t, "quay.io/ocp/release:4.5"), icspList); err == nil {
+			t.Errorf("expected an error for annotation %q", value)
+		}
+	}
+}
+
+func TestMirrorGroups(t *testing.T) {
+	icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+		withAnnotations(
+			newICSP("release",
+				rdm("quay.io/ocp/release", "a.cache.example.com/ocp/release", "b.cache.example.com/ocp/release", "b.cache.example.com/other/release"),
+			),
+			map[string]string{MirrorGroupsAnnotation: `{"cache": ["a.cache.example.com", "b.cache.example.com"]}`},
+		),
+		newICSP("other", rdm("quay.io/ocp/release", "b.cache.example.com/ocp/release", "registry.example.com/ocp/release")),
+	}
+	alternates, err := alternativeImageSources(mustParse(t, "quay.io/ocp/release@"+testDigest), icspList)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp/release@" + testDigest,
+		"a.cache.example.com/ocp/release@" + testDigest,
+		"b.cache.example.com/other/release@" + testDigest,
+		"registry.example.com/ocp/release@" + testDigest,
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestMirrorGroupsConflict(t *testing.T) {
+	icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+		withAnnotations(newICSP("a"), map[string]string{MirrorGroupsAnnotation: `{"one": ["a.example.com"]}`}),
+		withAnnotations(newICSP("b"), map[string]string{MirrorGroupsAnnotation: `{"two": ["a.example.com"]}`}),
+	}
+	if _, err := alternativeImageSources(mustParse(t, "quay.io/ocp/release:4.5"), icspList); err == nil {
+		t.Errorf("expected an error for a host assigned to two groups")
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+		withAnnotations(
+			newICSP("migration"),
+			map[string]string{RedirectsAnnotation: `{"quay.io/old/app": "quay.io/new/app", "quay.io/old": "quay.io/legacy"}`},
+		),
+		newICSP("new", rdm("quay.io/new/app", "registry.example.com/new/app")),
+		newICSP("old", rdm("quay.io/old/app", "registry.example.com/old/app")),
+	}
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "quay.io/old/app/component:v1",
+			expected: []string{
+				"quay.io/old/app/component:v1",
+				"quay.io/new/app/component:v1",
+				"registry.example.com/new/app/component:v1",
+			},
+		},
+		{
+			image:    "quay.io/old/other:v1",
+			expected: []string{"quay.io/old/other:v1", "quay.io/legacy/other:v1"},
+		},
+		{
+			image:    "quay.io/new/app:v1",
+			expected: []string{"quay.io/new/app:v1", "registry.example.com/new/app:v1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := alternativeImageSources(mustParse(t, tt.image), icspList)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+
+	invalid := []operatorv1alpha1.ImageContentSourcePolicy{
+		withAnnotations(newICSP("invalid"), map[string]string{RedirectsAnnotation: `["quay.io/old"]`}),
+	}
+	if _, err := alternativeImageSources(mustParse(t, "quay.io/old/app:v1"), invalid); err == nil {
+		t.Errorf("expected an error for an invalid redirect annotation")
+	}
+}
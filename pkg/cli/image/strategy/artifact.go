@@ -0,0 +1,124 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest/ocischema"
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/opencontainers/go-digest"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/library-go/pkg/image/registryclient"
+)
+
+// ArtifactFetcher retrieves the layer contents of an OCI artifact.
+type ArtifactFetcher interface {
+	FetchArtifact(ctx context.Context, ref reference.DockerImageReference) ([][]byte, error)
+}
+
+// WithPolicyArtifact loads policies from the layers of the OCI artifact at ref, each of
+// which must hold ImageContentSourcePolicy YAML. This allows the policy to be distributed
+// through the same mirrors as the content it describes.
+func WithPolicyArtifact(ref reference.DockerImageReference, fetcher ArtifactFetcher) Option {
+	return func(s *OnErrorStrategy) {
+		s.sources = append(s.sources, func(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+			return readICSPsFromArtifact(ctx, ref, fetcher, s.decode)
+		})
+	}
+}
+
+func readICSPsFromArtifact(ctx context.Context, ref reference.DockerImageReference, fetcher ArtifactFetcher, opts decodeOptions) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	if registryFetcher, ok := fetcher.(*RegistryArtifactFetcher); ok && registryFetcher.MaxSize == 0 {
+		limited := *registryFetcher
+		limited.MaxSize = opts.maxSize
+		fetcher = &limited
+	}
+	layers, err := fetcher.FetchArtifact(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch ImageContentSourcePolicy artifact %s: %v", ref.Exact(), err)
+	}
+	if len(layers) == 0 {
+		return nil, fmt.Errorf("ImageContentSourcePolicy artifact %s has no layers", ref.Exact())
+	}
+	var icspList []operatorv1alpha1.ImageContentSourcePolicy
+	for i, layer := range layers {
+		if opts.maxSize > 0 && int64(len(layer)) > opts.maxSize {
+			return nil, fmt.Errorf("layer %d of ImageContentSourcePolicy artifact %s exceeds the maximum size of %d bytes", i, ref.Exact(), opts.maxSize)
+		}
+		loaded, err := parseICSPs(layer, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse layer %d of ImageContentSourcePolicy artifact %s: %v", i, ref.Exact(), err)
+		}
+		icspList = append(icspList, loaded...)
+	}
+	return icspList, nil
+}
+
+// RegistryArtifactFetcher fetches artifacts from a container image registry.
+type RegistryArtifactFetcher struct {
+	Context  *registryclient.Context
+	Insecure bool
+	// MaxSize refuses layers larger than MaxSize bytes, before fetching them when their
+	// descriptor declares a larger size. Zero or less fetches layers of any size, and the
+	// strategy applies its policy size limit when it is zero.
+	MaxSize int64
+}
+
+// FetchArtifact retrieves the manifest at ref and returns the contents of its layers in order.
+func (f *RegistryArtifactFetcher) FetchArtifact(ctx context.Context, ref reference.DockerImageReference) ([][]byte, error) {
+	ref = ref.DockerClientDefaults()
+	repo, err := f.Context.Repository(ctx, ref.RegistryURL(), ref.RepositoryName(), f.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err := repo.Manifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var manifest distribution.Manifest
+	if len(ref.ID) > 0 {
+		manifest, err = manifests.Get(ctx, digest.Digest(ref.ID))
+	} else {
+		manifest, err = manifests.Get(ctx, "", distribution.WithTag(ref.Tag))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var descriptors []distribution.Descriptor
+	switch t := manifest.(type) {
+	case *ocischema.DeserializedManifest:
+		descriptors = t.Layers
+	case *schema2.DeserializedManifest:
+		descriptors = t.Layers
+	default:
+		return nil, fmt.Errorf("unsupported artifact manifest type %T", manifest)
+	}
+
+	blobs := repo.Blobs(ctx)
+	layers := make([][]byte, 0, len(descriptors))
+	for _, d := range descriptors {
+		if f.MaxSize > 0 && d.Size > f.MaxSize {
+			return nil, fmt.Errorf("layer %s of %d bytes exceeds the maximum size of %d bytes", d.Digest, d.Size, f.MaxSize)
+		}
+		data, err := f.readBlob(ctx, blobs, d.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve layer %s: %v", d.Digest, err)
+		}
+		layers = append(layers, data)
+	}
+	return layers, nil
+}
+
+// readBlob reads the blob dgst from blobs, failing once it grows past the size limit.
+func (f *RegistryArtifactFetcher) readBlob(ctx context.Context, blobs distribution.BlobStore, dgst digest.Digest) ([]byte, error) {
+	r, err := blobs.Open(ctx, dgst)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return readLimited(r, f.MaxSize)
+}
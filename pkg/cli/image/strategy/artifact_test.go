@@ -0,0 +1,66 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+type fakeArtifactFetcher struct {
+	layers map[string][][]byte
+}
+
+func (f *fakeArtifactFetcher) FetchArtifact(ctx context.Context, ref reference.DockerImageReference) ([][]byte, error) {
+	layers, ok := f.layers[ref.Exact()]
+	if !ok {
+		return nil, fmt.Errorf("artifact %s not found", ref.Exact())
+	}
+	return layers, nil
+}
+
+func TestWithPolicyArtifact(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/icsp.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact := mustParse(t, "registry.example.com/policies/icsp:v1")
+	fetcher := &fakeArtifactFetcher{layers: map[string][][]byte{
+		artifact.Exact(): {data},
+	}}
+	s := NewICSPOnErrorStrategy(nil, "", WithPolicyArtifact(artifact, fetcher))
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "registry.redhat.io/operators/etcd@"+testDigest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"registry.redhat.io/operators/etcd@" + testDigest,
+		"registry.example.com/operators/etcd@" + testDigest,
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestWithPolicyArtifactErrors(t *testing.T) {
+	artifact := mustParse(t, "registry.example.com/policies/icsp:v1")
+	tests := []struct {
+		name   string
+		layers map[string][][]byte
+	}{
+		{name: "missing artifact"},
+		{name: "no layers", layers: map[string][][]byte{artifact.Exact(): {}}},
+		{name: "invalid layer", layers: map[string][][]byte{artifact.Exact(): {[]byte("kind: Pod\n")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(nil, "", WithPolicyArtifact(artifact, &fakeArtifactFetcher{layers: tt.layers}))
+			if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp-test/release:4.5")); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
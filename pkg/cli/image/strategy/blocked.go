@@ -0,0 +1,59 @@
+package strategy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// WithBlockedRegistries never returns alternates on the given registries, which take the
+// form of spec.registrySources.blockedRegistries in the cluster image configuration: a
+// registry host with an optional port and repository path, or a wildcard domain such as
+// *.example.com. A blocked requested image is omitted from the alternates, leaving only
+// its mirrors. Each blocked alternate is reported as a warning.
+func WithBlockedRegistries(registries ...string) Option {
+	return func(s *OnErrorStrategy) {
+		for _, registry := range registries {
+			s.blockedRegistries = append(s.blockedRegistries, normalizeRepository(strings.ToLower(registry)))
+		}
+	}
+}
+
+// isBlocked returns true if ref is on one of the blocked registries.
+func (s *OnErrorStrategy) isBlocked(ref reference.DockerImageReference) bool {
+	repository := strings.ToLower(ref.DockerClientDefaults().AsRepository().Exact())
+	for _, blocked := range s.blockedRegistries {
+		if strings.HasPrefix(blocked, "*.") {
+			if strings.HasSuffix(registryHost(ref.DockerClientDefaults().Registry), blocked[1:]) {
+				return true
+			}
+			continue
+		}
+		if _, ok := matchesSource(repository, blocked); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// blockRegistries removes the alternates of r on blocked registries. The caller must hold
+// s.lock.
+func (s *OnErrorStrategy) blockRegistries(r *resolution) {
+	if len(s.blockedRegistries) == 0 {
+		return
+	}
+	r.filterMirrors(func(alternate Alternate) bool {
+		if !s.isBlocked(alternate.Ref) {
+			return true
+		}
+		s.warn(fmt.Sprintf("mirror %s declared by ImageContentSourcePolicy %s is on a blocked registry and will not be used", alternate.Ref.Exact(), alternate.Policy))
+		return false
+	}, SkipBlocked)
+	image := r.alternates[0].Ref
+	if s.isBlocked(image) {
+		s.warn(fmt.Sprintf("image %s is on a blocked registry and will only be retrieved from its mirrors", image.Exact()))
+		r.sourceOmitted = true
+		r.skipped = append(r.skipped, Skipped{Ref: image, Reason: SkipBlocked})
+	}
+}
@@ -0,0 +1,91 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestBlockedRegistries(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release",
+			"blocked.example.com/ocp/release",
+			"registry.example.com/ocp/release",
+			"cache.untrusted.example.net/ocp/release",
+			"registry.example.com/private/release",
+		)),
+		newICSP("hub", rdm("docker.io/library", "registry.example.com/library")),
+	}}
+	tests := []struct {
+		name     string
+		blocked  []string
+		image    string
+		expected []string
+	}{
+		{
+			name:    "mirrors on blocked hosts, wildcards and repositories",
+			blocked: []string{"blocked.example.com", "*.untrusted.example.net", "registry.example.com/private"},
+			image:   "quay.io/ocp/release:4.8",
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"registry.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			name:    "blocked source",
+			blocked: []string{"quay.io"},
+			image:   "quay.io/ocp/release:4.8",
+			expected: []string{
+				"blocked.example.com/ocp/release:4.8",
+				"registry.example.com/ocp/release:4.8",
+				"cache.untrusted.example.net/ocp/release:4.8",
+				"registry.example.com/private/release:4.8",
+			},
+		},
+		{
+			name:     "default registry",
+			blocked:  []string{"docker.io"},
+			image:    "docker.io/library/busybox:latest",
+			expected: []string{"registry.example.com/library/busybox:latest"},
+		},
+		{
+			name:    "port must match",
+			blocked: []string{"registry.example.com:5000"},
+			image:   "docker.io/library/busybox:latest",
+			expected: []string{
+				"docker.io/library/busybox:latest",
+				"registry.example.com/library/busybox:latest",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings := &bytes.Buffer{}
+			s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings), WithBlockedRegistries(tt.blocked...))
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+
+	warnings := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings), WithBlockedRegistries("blocked.example.com"))
+	explanation, err := s.Explain(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(explanation.Skipped) != 1 || explanation.Skipped[0].Reason != SkipBlocked {
+		t.Errorf("expected the blocked mirror to be skipped, got %v", explanation.Skipped)
+	}
+	if !strings.Contains(warnings.String(), "blocked.example.com/ocp/release:4.8 declared by ImageContentSourcePolicy release is on a blocked registry") {
+		t.Errorf("expected a warning for the blocked mirror, got %q", warnings.String())
+	}
+}
@@ -0,0 +1,41 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WithResolutionBudget bounds the time spent resolving the alternates of each image,
+// loading the policies and probing and routing the mirrors, to budget, independently of
+// the deadline of the caller. When the budget runs out the alternates known so far are
+// returned with a warning: the requested image alone if the policies could not be loaded
+// in time, or the mirrors in their resolved order if they could not all be probed.
+// Partial results are not cached, so later requests try again. Policy sources, probes and
+// the resolution webhook must honor the cancellation of their context for the budget to
+// take effect.
+func WithResolutionBudget(budget time.Duration) Option {
+	return func(s *OnErrorStrategy) {
+		s.resolutionBudget = budget
+	}
+}
+
+// budgetContext returns the context bounding the resolution of one image. The caller must
+// call the returned cancel function.
+func (s *OnErrorStrategy) budgetContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.resolutionBudget <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.resolutionBudget)
+}
+
+// overBudget returns true if the resolution budget of budgetCtx ran out while the context
+// of the caller, ctx, is still active.
+func (s *OnErrorStrategy) overBudget(ctx, budgetCtx context.Context) bool {
+	return s.resolutionBudget > 0 && budgetCtx.Err() != nil && ctx.Err() == nil
+}
+
+// budgetWarning reports that resolving image exceeded its budget while doing step.
+func (s *OnErrorStrategy) budgetWarning(image, step, outcome string) {
+	s.warn(fmt.Sprintf("resolving %s exceeded the resolution budget of %s while %s, %s", image, s.resolutionBudget, step, outcome))
+}
@@ -0,0 +1,123 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// slowICSPLister lists its policies after delay, unless ctx is done first.
+type slowICSPLister struct {
+	fakeICSPLister
+	delay time.Duration
+}
+
+func (f *slowICSPLister) List(ctx context.Context, opts metav1.ListOptions) (*operatorv1alpha1.ImageContentSourcePolicyList, error) {
+	select {
+	case <-time.After(f.delay):
+		return f.fakeICSPLister.List(ctx, opts)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestWithResolutionBudgetPolicyLoad(t *testing.T) {
+	client := &slowICSPLister{
+		fakeICSPLister: fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+			newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		}},
+		delay: time.Second,
+	}
+	warnings := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithResolutionBudget(20*time.Millisecond), WithWarnings(warnings))
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+
+	started := time.Now()
+	alternates, err := s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the resolution to stop at its budget, took %s", elapsed)
+	}
+	if expected := []string{"quay.io/ocp/release:4.8"}; !reflect.DeepEqual(expected, exactRefs(alternates)) {
+		t.Errorf("expected %v, got %v", expected, exactRefs(alternates))
+	}
+	if !strings.Contains(warnings.String(), "exceeded the resolution budget of 20ms while loading the policies") {
+		t.Errorf("expected a warning about the budget, got %q", warnings.String())
+	}
+
+	client.delay = 0
+	alternates, err = s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}; !reflect.DeepEqual(expected, exactRefs(alternates)) {
+		t.Errorf("expected the partial result not to be cached, got %v", exactRefs(alternates))
+	}
+
+	t.Run("caller cancelled", func(t *testing.T) {
+		client.delay = time.Second
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s := NewICSPOnErrorStrategy(client, "", WithResolutionBudget(time.Minute))
+		if _, err := s.OnFailure(ctx, image); err == nil {
+			t.Errorf("expected the cancellation of the caller to fail the resolution")
+		}
+	})
+}
+
+func TestWithResolutionBudgetProbes(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "slow.example.com/ocp/release", "down.example.com/ocp/release", "up.example.com/ocp/release")),
+	}}
+	var lock sync.Mutex
+	probes := 0
+	prober := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		lock.Lock()
+		probes++
+		lock.Unlock()
+		switch ref.Registry {
+		case "slow.example.com":
+			<-ctx.Done()
+			return ctx.Err()
+		case "down.example.com":
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	warnings := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithProbe(prober), WithResolutionBudget(20*time.Millisecond), WithWarnings(warnings))
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+	for i := 1; i <= 2; i++ {
+		alternates, err := s.OnFailure(context.Background(), image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{
+			"quay.io/ocp/release:4.8",
+			"slow.example.com/ocp/release:4.8",
+			"up.example.com/ocp/release:4.8",
+			"down.example.com/ocp/release:4.8",
+		}
+		if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected the mirror that was not probed in time to keep its place in %v, got %v", expected, actual)
+		}
+		if probes != 3*i {
+			t.Errorf("expected the partial result not to be cached, got %d probes after %d resolutions", probes, i)
+		}
+	}
+	if !strings.Contains(warnings.String(), "exceeded the resolution budget of 20ms while checking its mirrors") {
+		t.Errorf("expected a warning about the budget, got %q", warnings.String())
+	}
+}
@@ -0,0 +1,140 @@
+package strategy
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// WithResolutionCache keeps the alternates of at most size images, evicting the least
+// recently used, instead of keeping the alternates of every image for the lifetime of the
+// strategy. Entries are keyed by the fingerprint of the policies they were resolved
+// against, which are loaded again for every image unless Init was called, so that a
+// long-running service picks up policy changes without resolving unchanged images again.
+// Values below one keep a single image.
+func WithResolutionCache(size int) Option {
+	return func(s *OnErrorStrategy) {
+		if size < 1 {
+			size = 1
+		}
+		s.resolutions = newResolutionCache(size)
+	}
+}
+
+// resolutionKey identifies alternates in a resolutionCache.
+type resolutionKey struct {
+	alternatesKey
+	fingerprint string
+}
+
+// cachedResolution is an entry of a resolutionCache.
+type cachedResolution struct {
+	key        resolutionKey
+	alternates []Alternate
+}
+
+// resolutionCache is a least recently used cache of alternates. It is not safe for
+// concurrent use.
+type resolutionCache struct {
+	size    int
+	order   *list.List
+	entries map[resolutionKey]*list.Element
+}
+
+func newResolutionCache(size int) *resolutionCache {
+	return &resolutionCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[resolutionKey]*list.Element),
+	}
+}
+
+// get returns the alternates stored under key and marks them as recently used.
+func (c *resolutionCache) get(key resolutionKey) ([]Alternate, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*cachedResolution).alternates, true
+}
+
+// add stores alternates under key, evicting the least recently used entry when the cache
+// is full.
+func (c *resolutionCache) add(key resolutionKey, alternates []Alternate) {
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cachedResolution).alternates = alternates
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cachedResolution{key: key, alternates: alternates})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResolution).key)
+	}
+}
+
+// fingerprintFor returns the fingerprint of icspList, reusing the fingerprint of the
+// policies Init loaded. The caller must hold s.lock.
+func (s *OnErrorStrategy) fingerprintFor(icspList []operatorv1alpha1.ImageContentSourcePolicy) (string, error) {
+	if !s.initialized || !sameList(icspList, s.preloaded) {
+		return policyFingerprint(icspList)
+	}
+	if len(s.preloadedFingerprint) == 0 {
+		fingerprint, err := policyFingerprint(icspList)
+		if err != nil {
+			return "", err
+		}
+		s.preloadedFingerprint = fingerprint
+	}
+	return s.preloadedFingerprint, nil
+}
+
+// policyFingerprint returns a digest of the parts of icspList that affect resolution:
+// the name, kind, annotations and spec of each policy, in order.
+func policyFingerprint(icspList []operatorv1alpha1.ImageContentSourcePolicy) (string, error) {
+	type fingerprinted struct {
+		Name        string                                        `json:"name"`
+		Kind        string                                        `json:"kind"`
+		Annotations map[string]string                             `json:"annotations"`
+		Spec        operatorv1alpha1.ImageContentSourcePolicySpec `json:"spec"`
+	}
+	hash := sha256.New()
+	encoder := json.NewEncoder(hash)
+	for i := range icspList {
+		icsp := &icspList[i]
+		if err := encoder.Encode(fingerprinted{Name: icsp.Name, Kind: policyKind(icsp), Annotations: icsp.Annotations, Spec: icsp.Spec}); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WithMinimalResolution avoids allocating on the common path of an image that no policy
+// mirrors: once such an image is resolved, OnFailure returns the same single-element slice
+// for every later request of it. Callers must not modify the slices returned by OnFailure.
+func WithMinimalResolution() Option {
+	return func(s *OnErrorStrategy) {
+		s.minimal = true
+	}
+}
+
+// singleRef returns the shared slice holding only ref.
+func (s *OnErrorStrategy) singleRef(ref reference.DockerImageReference) []reference.DockerImageReference {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	refs, ok := s.singleRefs[ref]
+	if !ok {
+		if s.singleRefs == nil {
+			s.singleRefs = make(map[reference.DockerImageReference][]reference.DockerImageReference)
+		}
+		refs = []reference.DockerImageReference{ref}
+		s.singleRefs[ref] = refs
+	}
+	return refs
+}
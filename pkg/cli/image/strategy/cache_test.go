@@ -0,0 +1,146 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestResolutionCache(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("policy",
+			rdm("quay.io/ocp/a", "registry.example.com/ocp/a"),
+			rdm("quay.io/ocp/b", "registry.example.com/ocp/b"),
+			rdm("quay.io/ocp/c", "registry.example.com/ocp/c"),
+		),
+	}}
+	var resolved []string
+	prober := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		resolved = append(resolved, ref.RepositoryName())
+		return nil
+	})
+	s := NewICSPOnErrorStrategy(client, "", WithProbe(prober), WithProbeConcurrency(1), WithResolutionCache(2))
+	resolve := func(image string) []string {
+		alternates, err := s.OnFailure(context.Background(), mustParse(t, image))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return exactRefs(alternates)
+	}
+
+	resolve("quay.io/ocp/a:latest")
+	resolve("quay.io/ocp/b:latest")
+	resolve("quay.io/ocp/a:latest")
+	if expected := []string{"ocp/a", "ocp/b"}; !reflect.DeepEqual(resolved, expected) {
+		t.Fatalf("expected a cache hit, resolved %v", resolved)
+	}
+
+	resolved = nil
+	resolve("quay.io/ocp/c:latest")
+	resolve("quay.io/ocp/a:latest")
+	resolve("quay.io/ocp/b:latest")
+	if expected := []string{"ocp/c", "ocp/b"}; !reflect.DeepEqual(resolved, expected) {
+		t.Fatalf("expected the least recently used image to be evicted, resolved %v", resolved)
+	}
+
+	resolved = nil
+	client.items = []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("policy", rdm("quay.io/ocp/b", "mirror.example.com/ocp/b")),
+	}
+	got := resolve("quay.io/ocp/b:latest")
+	if expected := []string{"quay.io/ocp/b:latest", "mirror.example.com/ocp/b:latest"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v after the policy changed, got %v", expected, got)
+	}
+	if expected := []string{"ocp/b"}; !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("expected the image to be resolved again after the policy changed, resolved %v", resolved)
+	}
+}
+
+func TestPolicyFingerprint(t *testing.T) {
+	base := []operatorv1alpha1.ImageContentSourcePolicy{newICSP("policy", rdm("quay.io/ocp/release", "registry.example.com/ocp/release"))}
+	fingerprint, err := policyFingerprint(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withGen := []operatorv1alpha1.ImageContentSourcePolicy{withGeneration(base[0], 3)}
+	withGen[0].ResourceVersion = "42"
+	changed := []operatorv1alpha1.ImageContentSourcePolicy{newICSP("policy", rdm("quay.io/ocp/release", "other.example.com/ocp/release"))}
+
+	tests := []struct {
+		name     string
+		icspList []operatorv1alpha1.ImageContentSourcePolicy
+		same     bool
+	}{
+		{name: "server fields", icspList: withGen, same: true},
+		{name: "mirrors", icspList: changed},
+		{name: "annotations", icspList: []operatorv1alpha1.ImageContentSourcePolicy{withAnnotations(base[0], map[string]string{"a": "b"})}},
+		{name: "empty", icspList: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := policyFingerprint(tt.icspList)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same := got == fingerprint; same != tt.same {
+				t.Errorf("expected same fingerprint %t, got %s and %s", tt.same, fingerprint, got)
+			}
+		})
+	}
+}
+
+func TestWithMinimalResolution(t *testing.T) {
+	client := &fakeICSPLister{items: largePolicies(10)}
+	ctx := context.Background()
+	regular := NewICSPOnErrorStrategy(client, "")
+	minimal := NewICSPOnErrorStrategy(client, "", WithMinimalResolution())
+	for _, image := range []string{"registry.example.com/other/app:v1.0", "quay.io/team-1/app-4:v1.0", "busybox"} {
+		ref := mustParse(t, image)
+		expected, err := regular.OnFailure(ctx, ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			got, err := minimal.OnFailure(ctx, ref)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("%s: expected %v, got %v", image, exactRefs(expected), exactRefs(got))
+			}
+		}
+	}
+
+	unmatched := mustParse(t, "registry.example.com/other/app:v1.0")
+	if allocs := testing.AllocsPerRun(100, func() { minimal.OnFailure(ctx, unmatched) }); allocs != 0 {
+		t.Errorf("expected no allocations for a resolved image without mirrors, got %v", allocs)
+	}
+}
+
+func BenchmarkOnFailureNoMatch(b *testing.B) {
+	ref, err := ParseReference("registry.example.com/other/app:v1.0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for name, opts := range map[string][]Option{
+		"default": nil,
+		"minimal": {WithMinimalResolution()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			s := NewICSPOnErrorStrategy(&fakeICSPLister{items: largePolicies(100)}, "", opts...)
+			if err := s.Init(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.OnFailure(context.Background(), ref); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
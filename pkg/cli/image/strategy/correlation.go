@@ -0,0 +1,18 @@
+package strategy
+
+import "context"
+
+type correlationIDKey struct{}
+
+// WithCorrelationID returns a copy of ctx carrying id, which identifies the resolutions
+// made with it in the logs and the resolution log, so that the concurrent resolutions of a
+// batch job can be told apart.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or an empty string.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
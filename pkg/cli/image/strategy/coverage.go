@@ -0,0 +1,57 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// MirrorCoverage reports how many images of a set can be retrieved from a mirror.
+type MirrorCoverage struct {
+	// Total is the number of images checked.
+	Total int
+	// Covered is the number of images with at least one mirror.
+	Covered int
+	// Uncovered are the images without any mirror, in the order they were given.
+	Uncovered []reference.DockerImageReference
+}
+
+// Fraction returns the fraction of the images that have a mirror, or 1 when there are no
+// images.
+func (c *MirrorCoverage) Fraction() float64 {
+	if c.Total == 0 {
+		return 1
+	}
+	return float64(c.Covered) / float64(c.Total)
+}
+
+// Coverage resolves each of images and reports those without a mirror, so that a
+// disconnected installation can be checked for images that would only be retrieved from
+// their source. Alternates in the same repository as the requested image, such as the
+// requested image itself, are not mirrors.
+func (s *OnErrorStrategy) Coverage(ctx context.Context, images []reference.DockerImageReference) (*MirrorCoverage, error) {
+	coverage := &MirrorCoverage{Total: len(images)}
+	for _, image := range images {
+		alternates, err := s.OnFailure(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		if hasMirror(image, alternates) {
+			coverage.Covered++
+		} else {
+			coverage.Uncovered = append(coverage.Uncovered, image)
+		}
+	}
+	return coverage, nil
+}
+
+// hasMirror returns true if an alternate of image is in another repository.
+func hasMirror(image reference.DockerImageReference, alternates []reference.DockerImageReference) bool {
+	repository := image.AsRepository()
+	for _, alternate := range alternates {
+		if alternate.AsRepository() != repository {
+			return true
+		}
+	}
+	return false
+}
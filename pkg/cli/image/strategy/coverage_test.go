@@ -0,0 +1,45 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestCoverage(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp", "registry.example.com/ocp")),
+		newICSP("excluded", rdm("quay.io/ocp/secret", NeverMirror)),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	var images []reference.DockerImageReference
+	for _, image := range []string{
+		"quay.io/ocp/release:4.8",
+		"quay.io/ocp/installer@" + testDigest,
+		"quay.io/ocp/secret:latest",
+		"docker.io/library/busybox:latest",
+	} {
+		images = append(images, mustParse(t, image))
+	}
+	coverage, err := s.Coverage(context.Background(), images)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if coverage.Total != 4 || coverage.Covered != 2 || coverage.Fraction() != 0.5 {
+		t.Errorf("expected 2 of 4 images to be covered, got %d of %d (%v)", coverage.Covered, coverage.Total, coverage.Fraction())
+	}
+	if expected := []string{"quay.io/ocp/secret:latest", "docker.io/library/busybox:latest"}; !reflect.DeepEqual(exactRefs(coverage.Uncovered), expected) {
+		t.Errorf("expected %v to be uncovered, got %v", expected, exactRefs(coverage.Uncovered))
+	}
+
+	empty, err := s.Coverage(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if empty.Fraction() != 1 || len(empty.Uncovered) != 0 {
+		t.Errorf("expected an empty set to be fully covered, got %#v", empty)
+	}
+}
@@ -0,0 +1,89 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/docker/distribution/registry/client/auth"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/library-go/pkg/image/registryclient"
+	"github.com/openshift/oc/pkg/cli/image/manifest/dockercredentials"
+	"github.com/openshift/oc/pkg/helpers/image/credentialprovider"
+)
+
+// ScopedReference is an alternate location of an image and the credential scope needed to
+// read from it.
+type ScopedReference struct {
+	Ref reference.DockerImageReference
+	// Scope is the registry host, including any port, whose credentials authorize Ref.
+	Scope string
+}
+
+// CredentialScope returns the registry host whose credentials authorize ref, applying the
+// Docker Hub default for references without a registry. Hosts are lowercased since
+// credentials are looked up case-insensitively.
+func CredentialScope(ref reference.DockerImageReference) string {
+	return strings.ToLower(ref.DockerClientDefaults().Registry)
+}
+
+// OnFailureWithScopes returns the alternates of locator the same way as OnFailure, along
+// with the credential scope each of them needs, so that callers reading from a mirror can
+// select authentication for the mirror rather than for the source.
+func (s *OnErrorStrategy) OnFailureWithScopes(ctx context.Context, locator reference.DockerImageReference) ([]ScopedReference, error) {
+	alternates, err := s.OnFailure(ctx, locator)
+	if err != nil {
+		return nil, err
+	}
+	scoped := make([]ScopedReference, 0, len(alternates))
+	for _, alternate := range alternates {
+		scoped = append(scoped, ScopedReference{Ref: alternate, Scope: CredentialScope(alternate)})
+	}
+	return scoped, nil
+}
+
+// MirrorCredentials holds the registry credentials of several Docker auth files, for pulls
+// whose mirrors span registries that are authorized by different files.
+type MirrorCredentials struct {
+	keyring *credentialprovider.BasicDockerKeyring
+}
+
+// LoadMirrorCredentials merges the Docker config.json auth files at paths. When a registry
+// has credentials in more than one file, those of the earliest file are used, while
+// credentials for a more specific repository path always take precedence over those for
+// its registry.
+func LoadMirrorCredentials(paths ...string) (*MirrorCredentials, error) {
+	keyring := &credentialprovider.BasicDockerKeyring{}
+	for _, path := range paths {
+		cfg, err := credentialprovider.ReadSpecificDockerConfigJSONFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read auth file %s: %v", path, err)
+		}
+		keyring.Add(cfg)
+	}
+	return &MirrorCredentials{keyring: keyring}, nil
+}
+
+// Basic returns the username and password that authorize ref, or empty strings if no auth
+// file has credentials for it.
+func (c *MirrorCredentials) Basic(ref reference.DockerImageReference) (string, string) {
+	ref = ref.DockerClientDefaults()
+	return dockercredentials.BasicFromKeyring(c.keyring, &url.URL{Host: CredentialScope(ref), Path: "/" + ref.RepositoryName()})
+}
+
+// CredentialStore returns a credential store holding the merged credentials, for use with
+// registryclient.Context.WithCredentials.
+func (c *MirrorCredentials) CredentialStore() auth.CredentialStore {
+	return &mirrorCredentialStore{keyring: c.keyring, RefreshTokenStore: registryclient.NewRefreshTokenStore()}
+}
+
+type mirrorCredentialStore struct {
+	registryclient.RefreshTokenStore
+	keyring credentialprovider.DockerKeyring
+}
+
+func (s *mirrorCredentialStore) Basic(target *url.URL) (string, string) {
+	return dockercredentials.BasicFromKeyring(s.keyring, target)
+}
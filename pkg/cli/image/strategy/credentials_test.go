@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestOnFailureWithScopes(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("library",
+			rdm("docker.io/library", "registry.example.com:5000/library", "mirror.example.com/hub/library", "quay.io/library"),
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	scoped, err := s.OnFailureWithScopes(context.Background(), mustParse(t, "docker.io/library/busybox:latest"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"docker.io/library/busybox:latest":                 "docker.io",
+		"registry.example.com:5000/library/busybox:latest": "registry.example.com:5000",
+		"mirror.example.com/hub/library/busybox:latest":    "mirror.example.com",
+		"quay.io/library/busybox:latest":                   "quay.io",
+	}
+	actual := make(map[string]string, len(scoped))
+	for _, s := range scoped {
+		actual[s.Ref.Exact()] = s.Scope
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCredentialScope(t *testing.T) {
+	tests := map[string]string{
+		"busybox:latest": "docker.io",
+		"Registry.Example.com:5000/ns/app:latest": "registry.example.com:5000",
+		"quay.io/ocp/release@" + testDigest:       "quay.io",
+	}
+	for image, expected := range tests {
+		if actual := CredentialScope(mustParse(t, image)); actual != expected {
+			t.Errorf("%s: expected scope %q, got %q", image, expected, actual)
+		}
+	}
+}
+
+func TestLoadMirrorCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror-credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	first := filepath.Join(dir, "first.json")
+	if err := ioutil.WriteFile(first, []byte(`{"auths": {
+		"quay.io": {"username": "first", "password": "quay"},
+		"registry.example.com:5000": {"username": "first", "password": "registry"}
+	}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	second := filepath.Join(dir, "second.json")
+	if err := ioutil.WriteFile(second, []byte(`{"auths": {
+		"quay.io": {"username": "second", "password": "quay"},
+		"quay.io/ocp": {"username": "second", "password": "ocp"},
+		"mirror.example.com": {"username": "second", "password": "mirror"},
+		"https://index.docker.io/v1/": {"username": "second", "password": "hub"}
+	}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	credentials, err := LoadMirrorCredentials(first, second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		image    string
+		username string
+		password string
+	}{
+		{image: "quay.io/other/app:1.0", username: "first", password: "quay"},
+		{image: "quay.io/ocp/release@" + testDigest, username: "second", password: "ocp"},
+		{image: "registry.example.com:5000/ocp/release:4.8", username: "first", password: "registry"},
+		{image: "mirror.example.com/ocp/release:4.8", username: "second", password: "mirror"},
+		{image: "busybox:latest", username: "second", password: "hub"},
+		{image: "unknown.example.com/ocp/release:4.8"},
+	}
+	store := credentials.CredentialStore()
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref := mustParse(t, tt.image)
+			if username, password := credentials.Basic(ref); username != tt.username || password != tt.password {
+				t.Errorf("expected %s:%s, got %s:%s", tt.username, tt.password, username, password)
+			}
+			ref = ref.DockerClientDefaults()
+			if username, password := store.Basic(&url.URL{Scheme: "https", Host: ref.Registry, Path: "/v2/" + ref.RepositoryName()}); username != tt.username || password != tt.password {
+				t.Errorf("expected the credential store to return %s:%s, got %s:%s", tt.username, tt.password, username, password)
+			}
+		})
+	}
+
+	if _, err := LoadMirrorCredentials(first, filepath.Join(dir, "missing.json")); err == nil || !strings.Contains(err.Error(), "missing.json") {
+		t.Errorf("expected an error naming the missing auth file, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package strategy
+
+import (
+	"sort"
+	"strings"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// mirrorCycles returns the cycles found among the sources of icspList and their mirrors,
+// where a source leads to every other source overlapping one of its mirrors, such as a
+// source A mirrored to B while B is mirrored to A. Mirrors are not resolved transitively,
+// so a cycle does not fail resolution, but it usually means an entry is reversed. Each
+// cycle starts and ends with its alphabetically first source, and cycles are sorted.
+func mirrorCycles(icspList []operatorv1alpha1.ImageContentSourcePolicy) [][]string {
+	edges := make(map[string]map[string]bool)
+	for i := range icspList {
+		for _, rdm := range icspList[i].Spec.RepositoryDigestMirrors {
+			source := normalizeRepository(rdm.Source)
+			if edges[source] == nil {
+				edges[source] = make(map[string]bool)
+			}
+			for _, mirror := range rdm.Mirrors {
+				if strings.TrimSpace(mirror) != NeverMirror {
+					edges[source][normalizeRepository(mirror)] = true
+				}
+			}
+		}
+	}
+	sources := make([]string, 0, len(edges))
+	for source := range edges {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	next := make(map[string][]string, len(sources))
+	for _, source := range sources {
+		for _, target := range sources {
+			if target == source {
+				continue
+			}
+			for mirror := range edges[source] {
+				if overlaps(mirror, target) {
+					next[source] = append(next[source], target)
+					break
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(sources))
+	seen := make(map[string]bool)
+	var cycles [][]string
+	var path []string
+	var visit func(source string)
+	visit = func(source string) {
+		state[source] = visiting
+		path = append(path, source)
+		for _, target := range next[source] {
+			switch state[target] {
+			case unvisited:
+				visit(target)
+			case visiting:
+				start := 0
+				for path[start] != target {
+					start++
+				}
+				cycle := canonicalCycle(path[start:])
+				if key := strings.Join(cycle, " "); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[source] = visited
+	}
+	for _, source := range sources {
+		if state[source] == unvisited {
+			visit(source)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i], " ") < strings.Join(cycles[j], " ")
+	})
+	return cycles
+}
+
+// overlaps returns true if some repository is under both a and b.
+func overlaps(a, b string) bool {
+	if _, ok := matchesSource(a, b); ok {
+		return true
+	}
+	_, ok := matchesSource(b, a)
+	return ok
+}
+
+// canonicalCycle returns the sources of cycle rotated to start with the alphabetically
+// first of them, with that source repeated at the end.
+func canonicalCycle(cycle []string) []string {
+	first := 0
+	for i := range cycle {
+		if cycle[i] < cycle[first] {
+			first = i
+		}
+	}
+	canonical := make([]string, 0, len(cycle)+1)
+	canonical = append(canonical, cycle[first:]...)
+	canonical = append(canonical, cycle[:first]...)
+	return append(canonical, cycle[first])
+}
@@ -0,0 +1,74 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestMirrorCycles(t *testing.T) {
+	tests := []struct {
+		name     string
+		icspList []operatorv1alpha1.ImageContentSourcePolicy
+		expected [][]string
+	}{
+		{
+			name: "no cycle",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+				newICSP("mirror", rdm("registry.example.com/ocp/release", "backup.example.com/ocp/release")),
+			},
+		},
+		{
+			name: "across policies",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("forward", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+				newICSP("reverse", rdm("registry.example.com/ocp/release", "quay.io/ocp/release")),
+			},
+			expected: [][]string{{"quay.io/ocp/release", "registry.example.com/ocp/release", "quay.io/ocp/release"}},
+		},
+		{
+			name: "through a broader source",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp/release", "b.example.com/ocp/release")),
+				newICSP("b", rdm("b.example.com/ocp", "c.example.com/ocp")),
+				newICSP("c", rdm("c.example.com", "quay.io")),
+			},
+			expected: [][]string{{"b.example.com/ocp", "c.example.com", "quay.io/ocp/release", "b.example.com/ocp"}},
+		},
+		{
+			name: "never mirror",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("forward", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+				newICSP("reverse", rdm("registry.example.com/ocp/release", NeverMirror)),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mirrorCycles(tt.icspList); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMirrorCycleWarning(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("forward", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		newICSP("reverse", rdm("registry.example.com/ocp/release", "quay.io/ocp/release")),
+	}}
+	warnings := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings))
+	if err := s.Init(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "form a cycle quay.io/ocp/release -> registry.example.com/ocp/release -> quay.io/ocp/release"
+	if !strings.Contains(warnings.String(), expected) {
+		t.Errorf("expected the cycle to be reported at load, got %q", warnings.String())
+	}
+}
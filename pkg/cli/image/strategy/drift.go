@@ -0,0 +1,117 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// DriftType describes how a source entry of the cluster differs from the file it is
+// compared against.
+type DriftType string
+
+const (
+	// DriftAdded is an entry only declared by the cluster.
+	DriftAdded DriftType = "Added"
+	// DriftRemoved is an entry only declared by the file.
+	DriftRemoved DriftType = "Removed"
+	// DriftChanged is an entry declared by both with different mirrors.
+	DriftChanged DriftType = "Changed"
+)
+
+// PolicyDrift is a source entry of a policy that differs between the cluster and a file.
+type PolicyDrift struct {
+	Type   DriftType
+	Policy string
+	Source string
+	// Expected are the mirrors declared by the file, and Actual those declared by the
+	// cluster, in policy order. Either is empty when the entry is missing from it.
+	Expected []string
+	Actual   []string
+}
+
+// Drift compares the policies listed from the cluster with those read from the other
+// sources of the strategy, such as its policy file, so that changes made to the cluster
+// outside of the file can be detected. Entries are matched by policy name and normalized
+// source, and differ when their normalized mirrors or the order of them differ. The
+// differences are sorted by policy and source.
+func (s *OnErrorStrategy) Drift(ctx context.Context) ([]PolicyDrift, error) {
+	if s.icspClient == nil {
+		return nil, fmt.Errorf("no cluster to compare the policies against")
+	}
+	if len(s.sources) == 0 {
+		return nil, fmt.Errorf("no policy file to compare the cluster against")
+	}
+	var file []operatorv1alpha1.ImageContentSourcePolicy
+	for _, source := range s.sources {
+		loaded, err := source(ctx)
+		if err != nil {
+			return nil, err
+		}
+		file = append(file, loaded...)
+	}
+	list, err := s.icspClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list ImageContentSourcePolicies: %v", err)
+	}
+	return diffPolicies(list.Items, file), nil
+}
+
+// driftKey identifies a source entry across policy sets.
+type driftKey struct {
+	policy string
+	source string
+}
+
+// diffPolicies returns the entries of cluster that differ from those of file.
+func diffPolicies(cluster, file []operatorv1alpha1.ImageContentSourcePolicy) []PolicyDrift {
+	actual := declaredMirrors(cluster)
+	expected := declaredMirrors(file)
+	var drift []PolicyDrift
+	for key, mirrors := range actual {
+		want, ok := expected[key]
+		switch {
+		case !ok:
+			drift = append(drift, PolicyDrift{Type: DriftAdded, Policy: key.policy, Source: key.source, Actual: mirrors})
+		case !reflect.DeepEqual(mirrors, want):
+			drift = append(drift, PolicyDrift{Type: DriftChanged, Policy: key.policy, Source: key.source, Expected: want, Actual: mirrors})
+		}
+	}
+	for key, mirrors := range expected {
+		if _, ok := actual[key]; !ok {
+			drift = append(drift, PolicyDrift{Type: DriftRemoved, Policy: key.policy, Source: key.source, Expected: mirrors})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].Policy != drift[j].Policy {
+			return drift[i].Policy < drift[j].Policy
+		}
+		return drift[i].Source < drift[j].Source
+	})
+	return drift
+}
+
+// declaredMirrors returns the normalized mirrors of every source entry of icspList. The
+// mirrors of a source declared more than once by a policy are concatenated.
+func declaredMirrors(icspList []operatorv1alpha1.ImageContentSourcePolicy) map[driftKey][]string {
+	declared := make(map[driftKey][]string)
+	for _, icsp := range icspList {
+		for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+			key := driftKey{policy: icsp.Name, source: normalizeRepository(rdm.Source)}
+			mirrors, ok := declared[key]
+			if !ok {
+				mirrors = []string{}
+			}
+			for _, mirror := range rdm.Mirrors {
+				mirrors = append(mirrors, normalizeRepository(mirror))
+			}
+			declared[key] = mirrors
+		}
+	}
+	return declared
+}
@@ -0,0 +1,80 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestDiffPolicies(t *testing.T) {
+	file := []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "first.example.com/ocp/release", "second.example.com/ocp/release"),
+			rdm("quay.io/ocp/tools", "first.example.com/ocp/tools"),
+			rdm("quay.io/ocp/cli/", "first.example.com/ocp/cli/"),
+		),
+		newICSP("removed", rdm("quay.io/ocp/installer", "first.example.com/ocp/installer")),
+	}
+	cluster := []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "second.example.com/ocp/release", "first.example.com/ocp/release"),
+			rdm("quay.io/ocp/cli", "first.example.com/ocp/cli"),
+			rdm("quay.io/ocp/must-gather", "first.example.com/ocp/must-gather"),
+		),
+		newICSP("added", rdm("quay.io/ocp/installer", "first.example.com/ocp/installer")),
+	}
+	expected := []PolicyDrift{
+		{Type: DriftAdded, Policy: "added", Source: "quay.io/ocp/installer", Actual: []string{"first.example.com/ocp/installer"}},
+		{Type: DriftAdded, Policy: "release", Source: "quay.io/ocp/must-gather", Actual: []string{"first.example.com/ocp/must-gather"}},
+		{
+			Type:     DriftChanged,
+			Policy:   "release",
+			Source:   "quay.io/ocp/release",
+			Expected: []string{"first.example.com/ocp/release", "second.example.com/ocp/release"},
+			Actual:   []string{"second.example.com/ocp/release", "first.example.com/ocp/release"},
+		},
+		{Type: DriftRemoved, Policy: "release", Source: "quay.io/ocp/tools", Expected: []string{"first.example.com/ocp/tools"}},
+		{Type: DriftRemoved, Policy: "removed", Source: "quay.io/ocp/installer", Expected: []string{"first.example.com/ocp/installer"}},
+	}
+	if actual := diffPolicies(cluster, file); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %#v, got %#v", expected, actual)
+	}
+	if actual := diffPolicies(file, file); len(actual) != 0 {
+		t.Errorf("expected no drift between identical policies, got %#v", actual)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp-test/release", "registry.example.com/ocp-test/release"),
+			rdm("quay.io/ocp-test", "registry.example.com/ocp-test"),
+		),
+	}}
+	drift, err := NewICSPOnErrorStrategy(client, "testdata/icsp.yaml").Drift(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []PolicyDrift{
+		{Type: DriftRemoved, Policy: "operators", Source: "registry.redhat.io/operators", Expected: []string{"registry.example.com/operators"}},
+		{
+			Type:     DriftChanged,
+			Policy:   "release",
+			Source:   "quay.io/ocp-test/release",
+			Expected: []string{"registry.example.com/ocp-test/release", "mirror.example.com/ocp-test/release"},
+			Actual:   []string{"registry.example.com/ocp-test/release"},
+		},
+	}
+	if !reflect.DeepEqual(expected, drift) {
+		t.Errorf("expected %#v, got %#v", expected, drift)
+	}
+
+	if _, err := NewICSPOnErrorStrategy(client, "").Drift(context.Background()); err == nil {
+		t.Errorf("expected an error without a policy file")
+	}
+	if _, err := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml").Drift(context.Background()); err == nil {
+		t.Errorf("expected an error without a cluster")
+	}
+}
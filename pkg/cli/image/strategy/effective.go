@@ -0,0 +1,146 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// EffectivePolicy is the mirror configuration that results from loading, merging,
+// normalizing and filtering every policy.
+type EffectivePolicy struct {
+	// Sources are the distinct normalized sources, in the order they are first declared.
+	Sources []EffectiveSource
+}
+
+// EffectiveSource is the merged configuration of one source across all policies.
+type EffectiveSource struct {
+	Source string
+	// Mirrors are the normalized mirrors of Source that survive the configured filters, in
+	// the order they would be tried.
+	Mirrors []string
+	// Policies are the names of the policies declaring Source, in load order.
+	Policies []string
+	// Excluded is set when Source is excluded from mirroring with NeverMirror.
+	Excluded bool
+	// TagPatterns are the tag conditions restricting Source, or nil if it is unconditional.
+	TagPatterns []string
+	// RedirectTo is the source Source is redirected to, if any.
+	RedirectTo string
+}
+
+// EffectivePolicy returns the policies as the strategy applies them, so that tests and
+// tooling can check the final mirror configuration without resolving individual images.
+// Mirrors are subject to the allowed, denied, import and blocked registries, and ordered as
+// WithSortedMirrors and WithHomeRegistry would order them.
+func (s *OnErrorStrategy) EffectivePolicy(ctx context.Context) (*EffectivePolicy, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := mirrorGroups(icspList)
+	if err != nil {
+		return nil, err
+	}
+	policy := &EffectivePolicy{}
+	index := make(map[string]int)
+	seen := make(map[string]map[reference.DockerImageReference]bool)
+	entry := func(source string) *EffectiveSource {
+		i, ok := index[source]
+		if !ok {
+			i = len(policy.Sources)
+			index[source] = i
+			policy.Sources = append(policy.Sources, EffectiveSource{Source: source})
+			seen[source] = make(map[reference.DockerImageReference]bool)
+		}
+		return &policy.Sources[i]
+	}
+	for i := range icspList {
+		icsp := &icspList[i]
+		conditions, err := tagPatterns(icsp)
+		if err != nil {
+			return nil, err
+		}
+		declared, err := redirects(icsp)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sortedKeys(declared) {
+			e := entry(source)
+			if len(e.RedirectTo) == 0 {
+				e.RedirectTo = declared[source]
+			}
+		}
+		for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+			e := entry(normalizeRepository(rdm.Source))
+			if len(e.Policies) == 0 || e.Policies[len(e.Policies)-1] != icsp.Name {
+				e.Policies = append(e.Policies, icsp.Name)
+			}
+			e.TagPatterns = append(e.TagPatterns, conditions[e.Source]...)
+			if isExcluded(rdm) {
+				e.Excluded = true
+				continue
+			}
+			for _, mirror := range rdm.Mirrors {
+				mirrorRef, err := s.parse(normalizeRepository(mirror))
+				if err != nil {
+					return nil, fmt.Errorf("invalid mirror %q for source %q in ImageContentSourcePolicy %s: %v", mirror, rdm.Source, icsp.Name, err)
+				}
+				key := equivalenceKey(mirrorRef, groups)
+				if seen[e.Source][key] {
+					continue
+				}
+				seen[e.Source][key] = true
+				if s.offered(Alternate{Ref: mirrorRef, Policy: icsp.Name, Source: e.Source}) {
+					e.Mirrors = append(e.Mirrors, mirrorRef.Exact())
+				}
+			}
+		}
+	}
+	for i := range policy.Sources {
+		s.orderMirrors(policy.Sources[i].Mirrors)
+	}
+	return policy, nil
+}
+
+// offered returns true if alternate passes every configured mirror filter.
+func (s *OnErrorStrategy) offered(alternate Alternate) bool {
+	if (len(s.allowedMirrors) > 0 || len(s.deniedMirrors) > 0) && !s.mirrorAllowed(alternate) {
+		return false
+	}
+	if len(s.importRegistries) > 0 && !s.allowedForImport(alternate) {
+		return false
+	}
+	return !s.isBlocked(alternate.Ref)
+}
+
+// orderMirrors orders the mirrors of one source as resolve would.
+func (s *OnErrorStrategy) orderMirrors(mirrors []string) {
+	if s.sortMirrors {
+		sort.Strings(mirrors)
+	}
+	if len(s.homeRegistry) > 0 {
+		sort.SliceStable(mirrors, func(i, j int) bool {
+			return s.isHome(mirrors[i]) && !s.isHome(mirrors[j])
+		})
+	}
+}
+
+func (s *OnErrorStrategy) isHome(mirror string) bool {
+	ref, err := s.parse(mirror)
+	return err == nil && isSameOrSubdomain(registryHost(ref.Registry), s.homeRegistry)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
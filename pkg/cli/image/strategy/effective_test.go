@@ -0,0 +1,61 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestEffectivePolicy(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("cluster",
+			rdm("quay.io/ocp-test/release/", "blocked.example.com/ocp-test/release", "cluster.example.com/ocp-test/release", "registry.example.com/ocp-test/release"),
+			rdm("registry.redhat.io/operators/private", NeverMirror),
+		),
+		withAnnotations(
+			newICSP("conditional", rdm("quay.io/ocp-test/release", "tagged.example.com/ocp-test/release")),
+			map[string]string{
+				TagPatternsAnnotation: `{"quay.io/ocp-test/release": ["4.*"]}`,
+				RedirectsAnnotation:   `{"quay.io/old": "quay.io/ocp-test"}`,
+			},
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "testdata/icsp.yaml", WithClusterMerge(), WithBlockedRegistries("blocked.example.com"))
+	policy, err := s.EffectivePolicy(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &EffectivePolicy{Sources: []EffectiveSource{
+		{
+			Source: "quay.io/ocp-test/release",
+			Mirrors: []string{
+				"registry.example.com/ocp-test/release",
+				"mirror.example.com/ocp-test/release",
+				"cluster.example.com/ocp-test/release",
+				"tagged.example.com/ocp-test/release",
+			},
+			Policies:    []string{"release", "cluster", "conditional"},
+			TagPatterns: []string{"4.*"},
+		},
+		{Source: "quay.io/ocp-test", Mirrors: []string{"registry.example.com/ocp-test"}, Policies: []string{"release"}},
+		{Source: "registry.redhat.io/operators", Mirrors: []string{"registry.example.com/operators"}, Policies: []string{"operators"}},
+		{Source: "registry.redhat.io/operators/private", Policies: []string{"cluster"}, Excluded: true},
+		{Source: "quay.io/old", RedirectTo: "quay.io/ocp-test"},
+	}}
+	if !reflect.DeepEqual(expected, policy) {
+		t.Errorf("expected %#v, got %#v", expected, policy)
+	}
+
+	sorted, err := NewICSPOnErrorStrategy(client, "testdata/icsp.yaml", WithSortedMirrors(), WithHomeRegistry("registry.example.com")).EffectivePolicy(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mirrors := sorted.Sources[0].Mirrors; !reflect.DeepEqual(mirrors, []string{"registry.example.com/ocp-test/release", "mirror.example.com/ocp-test/release"}) {
+		t.Errorf("expected the file to override the cluster and the home registry to come first, got %v", mirrors)
+	}
+	if len(sorted.Sources) != 3 {
+		t.Errorf("expected only the sources of the file without WithClusterMerge, got %#v", sorted.Sources)
+	}
+}
@@ -0,0 +1,112 @@
+package strategy
+
+import (
+	"context"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// Explanation describes how the alternates of an image were resolved.
+type Explanation struct {
+	// Image is the requested image.
+	Image reference.DockerImageReference
+	// Alternates are the locations OnFailure would return, in order, with their origin.
+	Alternates []Alternate
+	// Policies are the policies with a source matching Image, in load order, along with
+	// their generation so that operators can confirm which revision was used.
+	Policies []MatchedPolicy
+	// Entries are the policy entries that contributed mirrors, in policy order, as they
+	// appear in the loaded policies.
+	Entries []MatchedEntry
+	// Skipped are the matching sources and mirrors that were not used, and why.
+	Skipped []Skipped
+	// Reason explains why no mirror was found, and is empty when there are mirrors.
+	Reason NoMirrorReason
+}
+
+// NoMirrorReason describes why an image has no mirrors.
+type NoMirrorReason string
+
+const (
+	// NoSourceMatched means no policy has a source the image falls under.
+	NoSourceMatched NoMirrorReason = "NoSourceMatched"
+	// EmptyMirrors means sources matched but declared no mirrors other than the image.
+	EmptyMirrors NoMirrorReason = "EmptyMirrors"
+	// MirrorsFiltered means the mirrors of the matching sources were all filtered out by
+	// the allowed, denied or blocked registries.
+	MirrorsFiltered NoMirrorReason = "MirrorsFiltered"
+	// TagConditionNotMet means the matching sources are restricted to other tags.
+	TagConditionNotMet NoMirrorReason = "TagConditionNotMet"
+	// SourceExcluded means the image is excluded from mirroring with NeverMirror.
+	SourceExcluded NoMirrorReason = "SourceExcluded"
+)
+
+// noMirrorReason returns why r has no mirrors, or an empty reason if it has some.
+func noMirrorReason(r *resolution) NoMirrorReason {
+	if len(r.alternates) > 1 {
+		return ""
+	}
+	if !r.sourceMatched {
+		return NoSourceMatched
+	}
+	reasons := make(map[SkipReason]bool)
+	for _, skipped := range r.skipped {
+		reasons[skipped.Reason] = true
+	}
+	switch {
+	case reasons[SkipFiltered], reasons[SkipBlocked]:
+		return MirrorsFiltered
+	case reasons[SkipTagCondition]:
+		return TagConditionNotMet
+	case reasons[SkipExcluded]:
+		return SourceExcluded
+	default:
+		return EmptyMirrors
+	}
+}
+
+// Explain resolves the alternates of locator like OnFailure, bypassing the cache, and
+// reports where each of them came from. The alternates are pinned, probed, routed and
+// ordered as OnFailure would, so that they are the locations it would return, and counted
+// in the metrics of the strategy.
+func (s *OnErrorStrategy) Explain(ctx context.Context, locator reference.DockerImageReference) (*Explanation, error) {
+	if err := validateLocator(locator); err != nil {
+		return nil, err
+	}
+	pinned := s.pinDigest(ctx, locator)
+	if err := s.checkDigest(pinned); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	budgetCtx, cancel := s.budgetContext(ctx)
+	defer cancel()
+	icspList, err := s.loadICSPs(budgetCtx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := s.resolve(pinned, icspList)
+	if err != nil {
+		return nil, err
+	}
+	s.probeMirrors(budgetCtx, r)
+	if warning := s.routeMirrors(budgetCtx, r); len(warning) > 0 {
+		s.warn(warning)
+	}
+	s.canonicalizeDigests(budgetCtx, r)
+	alternates := r.returned()
+	if s.adaptive {
+		alternates = s.orderBySuccess(alternates)
+	}
+	s.metrics.record(r)
+	return &Explanation{
+		Image:      locator,
+		Alternates: alternates,
+		Policies:   r.matched,
+		Entries:    r.entries,
+		Skipped:    r.skipped,
+		Reason:     noMirrorReason(r),
+	}, nil
+}
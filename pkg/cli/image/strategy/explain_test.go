@@ -0,0 +1,162 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func withGeneration(icsp operatorv1alpha1.ImageContentSourcePolicy, generation int64) operatorv1alpha1.ImageContentSourcePolicy {
+	icsp.Generation = generation
+	return icsp
+}
+
+func TestExplain(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		withGeneration(newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")), 3),
+		withGeneration(newICSP("unrelated", rdm("quay.io/other", "registry.example.com/other")), 1),
+		withGeneration(newICSP("namespace", rdm("quay.io/ocp", "mirror.example.com/ocp")), 7),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	image := mustParse(t, "quay.io/ocp/release@"+testDigest)
+	explanation, err := s.Explain(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if explanation.Image != image {
+		t.Errorf("expected image %s, got %s", image.Exact(), explanation.Image.Exact())
+	}
+	expectedPolicies := []MatchedPolicy{
+		{Name: "release", Generation: 3},
+		{Name: "namespace", Generation: 7},
+	}
+	if !reflect.DeepEqual(explanation.Policies, expectedPolicies) {
+		t.Errorf("expected policies %v, got %v", expectedPolicies, explanation.Policies)
+	}
+	expectedAlternates := []Alternate{
+		{Ref: image},
+		{Ref: mustParse(t, "registry.example.com/ocp/release@"+testDigest), Policy: "release", Source: "quay.io/ocp/release"},
+		{Ref: mustParse(t, "mirror.example.com/ocp/release@"+testDigest), Policy: "namespace", Source: "quay.io/ocp"},
+	}
+	if !reflect.DeepEqual(explanation.Alternates, expectedAlternates) {
+		t.Errorf("expected alternates %v, got %v", expectedAlternates, explanation.Alternates)
+	}
+}
+
+func TestExplainMatchesOnFailure(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("policy", rdm("quay.io/ocp/release", "down.example.com/ocp/release", "up.example.com/ocp/release")),
+	}}
+	prober := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		if ref.Registry == "down.example.com" {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	})
+	s := NewICSPOnErrorStrategy(client, "", WithProbe(prober))
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+	explanation, err := s.Explain(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	alternates, err := s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp/release:4.8",
+		"up.example.com/ocp/release:4.8",
+		"down.example.com/ocp/release:4.8",
+	}
+	if got := exactRefs(alternateRefs(explanation.Alternates)); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the probed order %v, got %v", expected, got)
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected OnFailure to return %v, got %v", expected, got)
+	}
+}
+
+func TestExplainEntries(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/other", "registry.example.com/other"),
+			rdm("quay.io/ocp/release/", "registry.example.com/ocp/release", "mirror.example.com/ocp/release"),
+		),
+		newICSP("namespace", rdm("quay.io/ocp", "mirror.example.com/ocp")),
+		withAnnotations(
+			newICSP("tagged", rdm("quay.io/ocp", "tagged.example.com/ocp")),
+			map[string]string{TagPatternsAnnotation: `{"quay.io/ocp": ["v*"]}`},
+		),
+	}}
+	explanation, err := NewICSPOnErrorStrategy(client, "").Explain(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []MatchedEntry{
+		{Policy: "release", Entry: rdm("quay.io/ocp/release/", "registry.example.com/ocp/release", "mirror.example.com/ocp/release")},
+		{Policy: "namespace", Entry: rdm("quay.io/ocp", "mirror.example.com/ocp")},
+	}
+	if !reflect.DeepEqual(expected, explanation.Entries) {
+		t.Errorf("expected entries %v, got %v", expected, explanation.Entries)
+	}
+	if len(explanation.Alternates) != 3 {
+		t.Errorf("expected the entries to produce every mirror, got %v", explanation.Alternates)
+	}
+}
+
+func TestExplainNoMirrorReason(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("empty", rdm("quay.io/ocp/empty")),
+		newICSP("self", rdm("quay.io/ocp/self", "quay.io/ocp/self")),
+		newICSP("filtered", rdm("quay.io/ocp/filtered", "denied.example.com/ocp/filtered", "elsewhere.example.net/ocp/filtered")),
+		newICSP("optout", rdm("quay.io/ocp/optout", NeverMirror)),
+		withAnnotations(
+			newICSP("tagged", rdm("quay.io/ocp/tagged", "registry.example.com/ocp/tagged")),
+			map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/tagged": ["4.*"]}`},
+		),
+		newICSP("mirrored", rdm("quay.io/ocp/mirrored", "registry.example.com/ocp/mirrored")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "",
+		WithAllowedMirrorRegistries("example.com"),
+		WithDeniedMirrorRegistries("denied.example.com"),
+	)
+	tests := []struct {
+		image    string
+		expected NoMirrorReason
+	}{
+		{image: "docker.io/library/busybox:latest", expected: NoSourceMatched},
+		{image: "quay.io/ocp/empty:latest", expected: EmptyMirrors},
+		{image: "quay.io/ocp/self:latest", expected: EmptyMirrors},
+		{image: "quay.io/ocp/filtered:latest", expected: MirrorsFiltered},
+		{image: "quay.io/ocp/optout:latest", expected: SourceExcluded},
+		{image: "quay.io/ocp/tagged:latest", expected: TagConditionNotMet},
+		{image: "quay.io/ocp/mirrored:latest", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			explanation, err := s.Explain(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if explanation.Reason != tt.expected {
+				t.Errorf("expected reason %q, got %q (skipped %v)", tt.expected, explanation.Reason, explanation.Skipped)
+			}
+		})
+	}
+
+	explanation, err := s.Explain(context.Background(), mustParse(t, "quay.io/ocp/filtered:latest"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedSkipped := []Skipped{
+		{Ref: mustParse(t, "denied.example.com/ocp/filtered:latest"), Policy: "filtered", Source: "quay.io/ocp/filtered", Reason: SkipFiltered},
+		{Ref: mustParse(t, "elsewhere.example.net/ocp/filtered:latest"), Policy: "filtered", Source: "quay.io/ocp/filtered", Reason: SkipFiltered},
+	}
+	if !reflect.DeepEqual(explanation.Skipped, expectedSkipped) {
+		t.Errorf("expected skipped %v, got %v", expectedSkipped, explanation.Skipped)
+	}
+}
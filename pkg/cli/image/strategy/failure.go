@@ -0,0 +1,81 @@
+package strategy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/docker/distribution/registry/api/errcode"
+	"github.com/docker/distribution/registry/client"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// FailureClass describes whether an attempt that failed may succeed if repeated.
+type FailureClass string
+
+const (
+	// FailureRetryable is a failure that may be transient, such as a 503 response.
+	FailureRetryable FailureClass = "Retryable"
+	// FailurePermanent is a failure that will recur, such as a 404 response.
+	FailurePermanent FailureClass = "Permanent"
+)
+
+// FailureClassifier classifies the error returned by an attempt against ref.
+type FailureClassifier func(ref reference.DockerImageReference, err error) FailureClass
+
+// WithFailureClassifier replaces DefaultFailureClassifier for errors passed to RecordFailure.
+func WithFailureClassifier(classifier FailureClassifier) Option {
+	return func(s *OnErrorStrategy) {
+		s.classifier = classifier
+	}
+}
+
+// RecordFailure reports that an attempt against ref failed with err and returns how the
+// failure was classified. Alternates with a permanent failure are marked as such in
+// subsequent attempt plans so that runners do not try them again.
+func (s *OnErrorStrategy) RecordFailure(ref reference.DockerImageReference, err error) FailureClass {
+	class := s.classifier(ref, err)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.failures[ref] = class
+	return class
+}
+
+// DefaultFailureClassifier treats registry responses with a client error status as
+// permanent, except for timeouts and rate limiting, and every other error as retryable.
+func DefaultFailureClassifier(ref reference.DockerImageReference, err error) FailureClass {
+	if isPermanentStatus(statusCode(err)) {
+		return FailurePermanent
+	}
+	return FailureRetryable
+}
+
+func isPermanentStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return code >= 400 && code < 500
+}
+
+// statusCode returns the HTTP status carried by a registry client error, or 0.
+func statusCode(err error) int {
+	switch t := err.(type) {
+	case errcode.Error:
+		return t.Code.Descriptor().HTTPStatusCode
+	case errcode.ErrorCode:
+		return t.Descriptor().HTTPStatusCode
+	case errcode.Errors:
+		if len(t) > 0 {
+			return statusCode(t[0])
+		}
+	case *client.UnexpectedHTTPResponseError:
+		return t.StatusCode
+	case *client.UnexpectedHTTPStatusError:
+		if code, err := strconv.Atoi(strings.SplitN(t.Status, " ", 2)[0]); err == nil {
+			return code
+		}
+	}
+	return 0
+}
@@ -0,0 +1,79 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/docker/distribution/registry/api/errcode"
+	v2 "github.com/docker/distribution/registry/api/v2"
+	"github.com/docker/distribution/registry/client"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+type httpStatusError int
+
+func (e httpStatusError) Error() string { return fmt.Sprintf("status %d", int(e)) }
+
+func TestRecordFailurePropagatesToPlan(t *testing.T) {
+	classified := 0
+	classifier := func(ref reference.DockerImageReference, err error) FailureClass {
+		classified++
+		if code, ok := err.(httpStatusError); ok && code == http.StatusNotFound {
+			return FailurePermanent
+		}
+		return FailureRetryable
+	}
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithFailureClassifier(classifier))
+	image := mustParse(t, "quay.io/ocp-test/release@"+testDigest)
+	notFound := mustParse(t, "registry.example.com/ocp-test/release@"+testDigest)
+	unavailable := mustParse(t, "mirror.example.com/ocp-test/release@"+testDigest)
+
+	if class := s.RecordFailure(notFound, httpStatusError(http.StatusNotFound)); class != FailurePermanent {
+		t.Errorf("expected a permanent failure, got %s", class)
+	}
+	if class := s.RecordFailure(unavailable, httpStatusError(http.StatusServiceUnavailable)); class != FailureRetryable {
+		t.Errorf("expected a retryable failure, got %s", class)
+	}
+	if classified != 2 {
+		t.Errorf("expected the injected classifier to be used, called %d times", classified)
+	}
+
+	plan, err := s.AttemptPlan(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[reference.DockerImageReference]bool{image: false, notFound: true, unavailable: false}
+	if len(plan) != len(expected) {
+		t.Fatalf("unexpected plan: %v", plan)
+	}
+	for _, attempt := range plan {
+		if attempt.Permanent != expected[attempt.Ref] {
+			t.Errorf("%s: expected permanent=%t", attempt.Ref.Exact(), expected[attempt.Ref])
+		}
+	}
+}
+
+func TestDefaultFailureClassifier(t *testing.T) {
+	ref := mustParse(t, "registry.example.com/ocp-test/release@"+testDigest)
+	tests := []struct {
+		err      error
+		expected FailureClass
+	}{
+		{err: v2.ErrorCodeManifestUnknown.WithArgs("latest"), expected: FailurePermanent},
+		{err: errcode.Errors{errcode.ErrorCodeUnauthorized}, expected: FailurePermanent},
+		{err: errcode.ErrorCodeUnavailable, expected: FailureRetryable},
+		{err: errcode.ErrorCodeTooManyRequests, expected: FailureRetryable},
+		{err: &client.UnexpectedHTTPResponseError{StatusCode: http.StatusNotFound, ParseErr: errors.New("bad")}, expected: FailurePermanent},
+		{err: &client.UnexpectedHTTPStatusError{Status: "503 Service Unavailable"}, expected: FailureRetryable},
+		{err: errors.New("connection reset by peer"), expected: FailureRetryable},
+	}
+	for _, tt := range tests {
+		if class := DefaultFailureClassifier(ref, tt.err); class != tt.expected {
+			t.Errorf("%v: expected %s, got %s", tt.err, tt.expected, class)
+		}
+	}
+}
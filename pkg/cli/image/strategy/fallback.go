@@ -0,0 +1,37 @@
+package strategy
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// WithFallbackRegistry mirrors every image whose repository no policy declares a source
+// for to the same repository under fallback, including its registry, for setups that pull
+// everything through a cache. For example, with the fallback cache.example.com/proxy the
+// image docker.io/library/busybox:latest is mirrored to
+// cache.example.com/proxy/docker.io/library/busybox:latest. Images under a declared
+// source are resolved as usual, even if the source contributes no mirrors for them.
+func WithFallbackRegistry(fallback string) Option {
+	return func(s *OnErrorStrategy) {
+		s.fallbackRegistry = normalizeRepository(fallback)
+	}
+}
+
+// applyFallback adds the fallback mirror of the requested image to r when no source
+// matched it.
+func (s *OnErrorStrategy) applyFallback(r *resolution) error {
+	if len(s.fallbackRegistry) == 0 || r.sourceMatched || len(r.alternates) > 1 {
+		return nil
+	}
+	image := r.alternates[0].Ref
+	repository := image.DockerClientDefaults().AsRepository().Exact()
+	mirrorRef, err := s.parse(s.fallbackRegistry + "/" + repository)
+	if err != nil {
+		return fmt.Errorf("invalid fallback mirror for %s under %s: %v", image.Exact(), s.fallbackRegistry, err)
+	}
+	mirrorRef.Tag, mirrorRef.ID = image.Tag, image.ID
+	klog.V(4).Infof("Using the fallback mirror %s for %s", mirrorRef.Exact(), image.Exact())
+	r.alternates = append(r.alternates, Alternate{Ref: mirrorRef, Policy: s.fallbackRegistry, Source: repository})
+	return nil
+}
@@ -0,0 +1,60 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithFallbackRegistry(t *testing.T) {
+	tagged := newICSP("tagged", rdm("quay.io/ocp/tagged", "registry.example.com/ocp/tagged"))
+	tagged.Annotations = map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/tagged": ["4.*"]}`}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		newICSP("excluded", rdm("quay.io/ocp/secret", NeverMirror)),
+		tagged,
+	}}
+	s := NewICSPOnErrorStrategy(client, "", WithFallbackRegistry("cache.example.com/proxy/"))
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image:    "docker.io/library/busybox:latest",
+			expected: []string{"docker.io/library/busybox:latest", "cache.example.com/proxy/docker.io/library/busybox:latest"},
+		},
+		{
+			image:    "busybox@" + testDigest,
+			expected: []string{"busybox@" + testDigest, "cache.example.com/proxy/docker.io/library/busybox@" + testDigest},
+		},
+		{
+			image:    "quay.io/other/app:1.0",
+			expected: []string{"quay.io/other/app:1.0", "cache.example.com/proxy/quay.io/other/app:1.0"},
+		},
+		{
+			image:    "quay.io/ocp/release:4.8",
+			expected: []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"},
+		},
+		{
+			image:    "quay.io/ocp/secret:4.8",
+			expected: []string{"quay.io/ocp/secret:4.8"},
+		},
+		{
+			image:    "quay.io/ocp/tagged:latest",
+			expected: []string{"quay.io/ocp/tagged:latest"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"net"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	configv1 "github.com/openshift/api/config/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// WithAllowedMirrorRegistries only returns mirrors on the given registry domains or their
+// subdomains. The requested image is always returned.
+func WithAllowedMirrorRegistries(domains ...string) Option {
+	return func(s *OnErrorStrategy) {
+		s.allowedMirrors = append(s.allowedMirrors, normalizeDomains(domains)...)
+	}
+}
+
+// WithDeniedMirrorRegistries never returns mirrors on the given registry domains or their
+// subdomains. Denied domains take precedence over allowed ones.
+func WithDeniedMirrorRegistries(domains ...string) Option {
+	return func(s *OnErrorStrategy) {
+		s.deniedMirrors = append(s.deniedMirrors, normalizeDomains(domains)...)
+	}
+}
+
+// WithAllowedRegistriesForImport only returns mirrors on the given registries, taken from
+// spec.allowedRegistriesForImport of the cluster image configuration, for resolutions that
+// feed an image import which the cluster would otherwise reject. A domain name without a
+// port matches the standard ports, and may start with a wildcard such as *.example.com.
+// The requested image is always returned.
+func WithAllowedRegistriesForImport(locations []configv1.RegistryLocation) Option {
+	return func(s *OnErrorStrategy) {
+		s.importRegistries = append(s.importRegistries, locations...)
+	}
+}
+
+// allowedForImport returns true if alternate is on one of the registries allowed for import.
+func (s *OnErrorStrategy) allowedForImport(alternate Alternate) bool {
+	for _, location := range s.importRegistries {
+		if registryLocationMatches(alternate.Ref, location.DomainName) {
+			return true
+		}
+	}
+	return false
+}
+
+// registryLocationMatches returns true if the registry of ref is domainName.
+func registryLocationMatches(ref reference.DockerImageReference, domainName string) bool {
+	domainName = strings.ToLower(normalizeRepository(domainName))
+	registry := strings.ToLower(ref.DockerClientDefaults().Registry)
+	host, port, err := net.SplitHostPort(registry)
+	if err != nil {
+		host, port = registry, ""
+	}
+	wantHost, wantPort, err := net.SplitHostPort(domainName)
+	if err != nil {
+		wantHost, wantPort = domainName, ""
+	}
+	switch wantPort {
+	case "":
+		if port != "" && port != "80" && port != "443" {
+			return false
+		}
+	default:
+		if port != wantPort {
+			return false
+		}
+	}
+	if strings.HasPrefix(wantHost, "*.") {
+		return strings.HasSuffix(host, wantHost[1:])
+	}
+	return host == wantHost
+}
+
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		normalized = append(normalized, registryHost(normalizeRepository(domain)))
+	}
+	return normalized
+}
+
+// mirrorAllowed returns true if the allowed and denied mirror registries permit alternate.
+func (s *OnErrorStrategy) mirrorAllowed(alternate Alternate) bool {
+	host := registryHost(alternate.Ref.Registry)
+	for _, domain := range s.deniedMirrors {
+		if isSameOrSubdomain(host, domain) {
+			return false
+		}
+	}
+	if len(s.allowedMirrors) == 0 {
+		return true
+	}
+	for _, domain := range s.allowedMirrors {
+		if isSameOrSubdomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterMirrors removes the mirrors for which allowed returns false, recording them as
+// skipped for reason. The requested image is not filtered.
+func (r *resolution) filterMirrors(allowed func(Alternate) bool, reason SkipReason) {
+	if len(r.alternates) < 2 {
+		return
+	}
+	kept := r.alternates[:1]
+	for _, alternate := range r.alternates[1:] {
+		if allowed(alternate) {
+			kept = append(kept, alternate)
+			continue
+		}
+		klog.V(4).Infof("Skipping mirror %s of %s: %s", alternate.Ref.Exact(), alternate.Source, reason)
+		r.skipped = append(r.skipped, Skipped{Ref: alternate.Ref, Policy: alternate.Policy, Source: alternate.Source, Reason: reason})
+	}
+	r.alternates = kept
+}
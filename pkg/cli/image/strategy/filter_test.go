@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestAllowedRegistriesForImport(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release",
+			"registry.example.com/ocp/release",
+			"registry.example.com:443/ocp/release",
+			"registry.example.com:5000/ocp/release",
+			"cache.mirrors.example.net/ocp/release",
+			"mirrors.example.net/ocp/release",
+			"untrusted.example.org/ocp/release",
+		)),
+	}}
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+
+	alternates, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alternates) != 7 {
+		t.Errorf("expected every mirror without the option, got %v", exactRefs(alternates))
+	}
+
+	s := NewICSPOnErrorStrategy(client, "", WithAllowedRegistriesForImport([]configv1.RegistryLocation{
+		{DomainName: "registry.example.com"},
+		{DomainName: "*.mirrors.example.net"},
+	}))
+	alternates, err = s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp/release:4.8",
+		"registry.example.com/ocp/release:4.8",
+		"registry.example.com:443/ocp/release:4.8",
+		"cache.mirrors.example.net/ocp/release:4.8",
+	}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestRegistryLocationMatches(t *testing.T) {
+	tests := []struct {
+		image      string
+		domainName string
+		expected   bool
+	}{
+		{image: "registry.example.com/app", domainName: "registry.example.com", expected: true},
+		{image: "Registry.Example.com/app", domainName: "registry.example.com", expected: true},
+		{image: "registry.example.com:80/app", domainName: "registry.example.com", expected: true},
+		{image: "registry.example.com:5000/app", domainName: "registry.example.com", expected: false},
+		{image: "registry.example.com:5000/app", domainName: "registry.example.com:5000", expected: true},
+		{image: "registry.example.com/app", domainName: "registry.example.com:5000", expected: false},
+		{image: "busybox", domainName: "docker.io", expected: true},
+		{image: "a.b.example.com/app", domainName: "*.example.com", expected: true},
+		{image: "example.com/app", domainName: "*.example.com", expected: false},
+	}
+	for _, tt := range tests {
+		if actual := registryLocationMatches(mustParse(t, tt.image), tt.domainName); actual != tt.expected {
+			t.Errorf("%s against %s: expected %t, got %t", tt.image, tt.domainName, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ResolveHelmValues resolves the alternates of every image referenced by the Helm values
+// file data, so that the images of a chart can be mirrored before it is installed in a
+// disconnected cluster. Images are found under keys named image, or ending in Image such
+// as sidecarImage, whose value is either a full reference or a map following the common
+// chart convention of a repository with an optional registry, tag and digest, such as
+// {registry: quay.io, repository: ocp/app, tag: "1.0"}. The result is keyed by the path
+// of the image within the values, e.g. sidecar.image or workers[0].image.
+func (s *OnErrorStrategy) ResolveHelmValues(ctx context.Context, data []byte) (map[string][]reference.DockerImageReference, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unable to parse Helm values: %v", err)
+	}
+	specs := make(map[string]string)
+	helmImages("", values, specs)
+	images := make(map[string][]reference.DockerImageReference, len(specs))
+	for path, spec := range specs {
+		ref, err := s.parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid image %q: %v", path, spec, err)
+		}
+		alternates, err := s.OnFailure(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		images[path] = alternates
+	}
+	return images, nil
+}
+
+// helmImages adds the images found in value, which is at path within the values, to
+// specs keyed by their path.
+func helmImages(path string, value interface{}, specs map[string]string) {
+	switch t := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if len(path) > 0 {
+				child = path + "." + key
+			}
+			if isHelmImageKey(key) {
+				if spec, ok := helmImage(t[key]); ok {
+					specs[child] = spec
+					continue
+				}
+			}
+			helmImages(child, t[key], specs)
+		}
+	case []interface{}:
+		for i, item := range t {
+			helmImages(fmt.Sprintf("%s[%d]", path, i), item, specs)
+		}
+	}
+}
+
+// isHelmImageKey returns true if key conventionally holds an image.
+func isHelmImageKey(key string) bool {
+	return key == "image" || strings.HasSuffix(key, "Image")
+}
+
+// helmImage returns the image reference value describes, if it is a non-empty string or a
+// map with a repository.
+func helmImage(value interface{}) (string, bool) {
+	switch t := value.(type) {
+	case string:
+		return t, len(t) > 0
+	case map[string]interface{}:
+		repository, ok := t["repository"].(string)
+		if !ok || len(repository) == 0 {
+			return "", false
+		}
+		spec := repository
+		if registry := helmScalar(t["registry"]); len(registry) > 0 {
+			spec = strings.TrimRight(registry, "/") + "/" + spec
+		}
+		if tag := helmScalar(t["tag"]); len(tag) > 0 {
+			spec += ":" + tag
+		}
+		if digest := helmScalar(t["digest"]); len(digest) > 0 {
+			spec += "@" + digest
+		}
+		return spec, true
+	}
+	return "", false
+}
+
+// helmScalar returns value as a string. Unquoted numbers such as tag: 1.36 are formatted
+// back from their parsed value, so a tag like 1.20 loses its trailing zero, as it does in
+// Helm itself.
+func helmScalar(value interface{}) string {
+	switch t := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	default:
+		return fmt.Sprint(t)
+	}
+}
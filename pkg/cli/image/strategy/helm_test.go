@@ -0,0 +1,49 @@
+package strategy
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestResolveHelmValues(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/helm-values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("charts",
+			rdm("quay.io/ocp", "registry.example.com/ocp"),
+			rdm("docker.io/library", "registry.example.com/library"),
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	images, err := s.ResolveHelmValues(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"image":            {"quay.io/ocp/app:1.0", "registry.example.com/ocp/app:1.0"},
+		"initImage":        {"quay.io/ocp/init:1.5", "registry.example.com/ocp/init:1.5"},
+		"sidecar.image":    {"quay.io/ocp/sidecar@" + testDigest, "registry.example.com/ocp/sidecar@" + testDigest},
+		"workers[0].image": {"busybox:1.36", "registry.example.com/library/busybox:1.36"},
+	}
+	got := make(map[string][]string, len(images))
+	for path, alternates := range images {
+		got[path] = exactRefs(alternates)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := s.ResolveHelmValues(context.Background(), []byte("image: Invalid//Image\n")); err == nil || !strings.Contains(err.Error(), `image: invalid image "Invalid//Image"`) {
+		t.Errorf("expected an invalid image error naming its path, got %v", err)
+	}
+	if _, err := s.ResolveHelmValues(context.Background(), []byte("image: [")); err == nil {
+		t.Errorf("expected a parse error")
+	}
+}
@@ -0,0 +1,969 @@
+package strategy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	configv1 "github.com/openshift/api/config/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ICSPLister lists the ImageContentSourcePolicy objects defined on a cluster. It is
+// satisfied by the typed operator/v1alpha1 ImageContentSourcePolicy client.
+type ICSPLister interface {
+	List(ctx context.Context, opts metav1.ListOptions) (*operatorv1alpha1.ImageContentSourcePolicyList, error)
+}
+
+// OnErrorStrategy resolves the alternate locations an image may be retrieved from when
+// a request against its source fails, using the mirrors declared by ImageContentSourcePolicy
+// objects loaded from a file or read from a cluster.
+type OnErrorStrategy struct {
+	lock sync.Mutex
+
+	icspClient     ICSPLister
+	icspFile       string
+	opts           []Option
+	sources        []policySource
+	mergeCluster   bool
+	kindPrecedence []string
+	digestOnly     bool
+	newestFirst    bool
+	overlays       []func() (*PolicyOverlay, error)
+	decode         decodeOptions
+	parse          ReferenceParser
+	httpClient     *http.Client
+
+	// requiredAnnotations are the annotations a policy must carry to be used.
+	requiredAnnotations map[string]string
+	// owners are the objects one of which must own a policy for it to be used.
+	owners []metav1.OwnerReference
+
+	overrideFiles []string
+	overrides     map[reference.DockerImageReference][]Alternate
+
+	homeRegistry   string
+	preferSameTLD  bool
+	hostAliases    map[string]string
+	rewrites       []mirrorRewrite
+	sortMirrors    bool
+	shuffleMirrors bool
+	shuffleSeed    int64
+	attemptTimeout time.Duration
+	classifier     FailureClassifier
+	failures       map[reference.DockerImageReference]FailureClass
+	adaptive       bool
+	successes      map[string]int
+	minimal        bool
+	singleRefs     map[reference.DockerImageReference][]reference.DockerImageReference
+	metrics        Metrics
+
+	prober           Prober
+	probeSource      bool
+	digestResolver   DigestResolver
+	canonicalDigests DigestResolver
+	tagDigests       *tagDigestCache
+	requireDigest    bool
+	probeConcurrency int
+	resolutionBudget time.Duration
+	webhookURL       string
+	webhookClient    *http.Client
+
+	allowedMirrors    []string
+	deniedMirrors     []string
+	blockedRegistries []string
+	retainSource      bool
+	fallbackRegistry  string
+	importRegistries  []configv1.RegistryLocation
+	insecureMirrors   []string
+	insecureConf      map[string][]string
+
+	minimumMirrors      int
+	refuseUnderMirrored bool
+	sourceLimits        map[string]int
+
+	isPublic            HostClassifier
+	refusePublicMirrors bool
+
+	warnings      io.Writer
+	warned        map[string]bool
+	resolutionLog io.Writer
+
+	// preloaded holds the policies loaded by Init, which are used instead of reloading them.
+	preloaded      []operatorv1alpha1.ImageContentSourcePolicy
+	preloadedIndex *policyIndex
+	initialized    bool
+	// snapshot is set on strategies returned by Snapshot, whose policies never change.
+	snapshot bool
+
+	namespaceOverlays map[string][]PolicyOverlay
+	signatureStores   []SignatureStore
+
+	alternates  map[alternatesKey][]Alternate
+	resolutions *resolutionCache
+	// preloadedFingerprint is the fingerprint of preloaded, computed on first use.
+	preloadedFingerprint string
+}
+
+// policySource loads policies from somewhere other than the cluster, such as a file.
+type policySource func(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error)
+
+// decodeOptions controls how policy documents are decoded.
+type decodeOptions struct {
+	// strict rejects documents with fields that are not part of the schema.
+	strict bool
+	// skipOtherKinds ignores documents that are not policies instead of failing.
+	skipOtherKinds bool
+	// maxSize and maxDocuments bound the size of a policy file and the number of
+	// documents in it, if set.
+	maxSize      int64
+	maxDocuments int
+	// parse validates the mirrors of each document as it is decoded, if set, so that an
+	// invalid policy is reported with its document.
+	parse ReferenceParser
+}
+
+// Option customizes an OnErrorStrategy.
+type Option func(*OnErrorStrategy)
+
+// WithStrictDecoding rejects policy documents containing unknown or misspelled fields,
+// such as "mirror" instead of "mirrors", which are otherwise silently ignored.
+func WithStrictDecoding() Option {
+	return func(s *OnErrorStrategy) {
+		s.decode.strict = true
+	}
+}
+
+// WithOtherKindsIgnored ignores every document of a policy stream that is not an
+// ImageContentSourcePolicy, ImageDigestMirrorSet or ImageTagMirrorSet, so that the output
+// of kustomize build, which usually holds other objects as well, can be passed as is.
+func WithOtherKindsIgnored() Option {
+	return func(s *OnErrorStrategy) {
+		s.decode.skipOtherKinds = true
+	}
+}
+
+// WithInlinePolicy supplies ImageContentSourcePolicy YAML directly instead of through a
+// file. The content is parsed exactly as a file passed to NewICSPOnErrorStrategy would be,
+// and is used in addition to that file.
+func WithInlinePolicy(yaml string) Option {
+	return func(s *OnErrorStrategy) {
+		s.sources = append(s.sources, func(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+			data, err := readLimited(strings.NewReader(yaml), s.decode.maxSize)
+			if err != nil {
+				return nil, fmt.Errorf("inline ImageContentSourcePolicy %v", err)
+			}
+			icspList, err := parseICSPs(data, s.decode)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse inline ImageContentSourcePolicy: %v", err)
+			}
+			return icspList, nil
+		})
+	}
+}
+
+// WithPolicyFiles reads policies from each of paths, which may also be URLs, in addition
+// to the file passed to NewICSPOnErrorStrategy.
+//
+// The mirrors of an image are ordered by where they were loaded from, so merging several
+// files always gives the same order. Sources are loaded in a fixed order: the file passed
+// to NewICSPOnErrorStrategy, then those added by options in the order the options are
+// given, with paths in the order listed, then the cluster if it is merged. Mirrors are
+// ordered by that load order first, then by the order of the policies within a file, of
+// the entries within a policy and of the mirrors within an entry. A mirror declared more
+// than once keeps its earliest position. WithNewestPoliciesFirst and WithKindPrecedence
+// reorder policies stably, so load order still breaks their ties.
+func WithPolicyFiles(paths ...string) Option {
+	return func(s *OnErrorStrategy) {
+		for _, path := range paths {
+			s.sources = append(s.sources, s.fileSource(path))
+		}
+	}
+}
+
+// fileSource returns a source reading the policies of the file or URL at path.
+func (s *OnErrorStrategy) fileSource(path string) policySource {
+	return func(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+		if isPolicyURL(path) {
+			return readICSPsFromURL(ctx, s.httpClient, path, s.decode)
+		}
+		return readICSPsFromFile(path, s.decode)
+	}
+}
+
+// WithClusterMerge lists the policies of the cluster in addition to those loaded from a
+// file or another source, instead of ignoring the cluster when a source is set. The
+// policies of the sources come first, so their mirrors are tried before those of the
+// cluster.
+func WithClusterMerge() Option {
+	return func(s *OnErrorStrategy) {
+		s.mergeCluster = true
+	}
+}
+
+// WithWarnings writes warnings about the loaded policies to w instead of the log.
+func WithWarnings(w io.Writer) Option {
+	return func(s *OnErrorStrategy) {
+		s.warnings = w
+	}
+}
+
+// NewICSPOnErrorStrategy returns a strategy that looks up alternate image sources from
+// ImageContentSourcePolicies. If icspFile or another policy source is set the policies
+// are read from them, otherwise they are listed with icspClient, unless WithClusterMerge
+// combines both. Either may be empty, and icspFile may be an http or https URL.
+func NewICSPOnErrorStrategy(icspClient ICSPLister, icspFile string, opts ...Option) *OnErrorStrategy {
+	s := &OnErrorStrategy{
+		icspClient: icspClient,
+		icspFile:   icspFile,
+		opts:       opts,
+		alternates: make(map[alternatesKey][]Alternate),
+		warned:     make(map[string]bool),
+		parse:      ParseReference,
+		httpClient: http.DefaultClient,
+		decode:     decodeOptions{maxSize: DefaultMaxPolicySize, maxDocuments: DefaultMaxPolicyDocuments},
+
+		attemptTimeout: DefaultAttemptTimeout,
+		classifier:     DefaultFailureClassifier,
+		failures:       make(map[reference.DockerImageReference]FailureClass),
+		successes:      make(map[string]int),
+
+		probeConcurrency: DefaultProbeConcurrency,
+	}
+	if len(icspFile) > 0 {
+		s.sources = append(s.sources, s.fileSource(icspFile))
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.decode.parse = s.parse
+	return s
+}
+
+// FirstRequest returns the locations to try before any failure has occurred, which is
+// only the requested image.
+func (s *OnErrorStrategy) FirstRequest(ctx context.Context, locator reference.DockerImageReference) ([]reference.DockerImageReference, error) {
+	return []reference.DockerImageReference{locator}, nil
+}
+
+// OnFailure returns the requested image followed by every mirror that the loaded policies
+// declare for it, in policy order and without duplicates. The requested image is returned
+// in the form it was given in, so an unqualified image such as busybox is matched as
+// docker.io/library/busybox but not rewritten to it. An image with both a tag and a
+// digest is pulled by digest from every alternate, and its tag is only used to match tag
+// conditions.
+func (s *OnErrorStrategy) OnFailure(ctx context.Context, locator reference.DockerImageReference) ([]reference.DockerImageReference, error) {
+	return s.onFailure(ctx, "", locator)
+}
+
+// OnFailureWithSources returns the alternates of locator in the same order as OnFailure,
+// each with the policy and source that contributed it, so that callers can report which
+// source a mirror was chosen for.
+func (s *OnErrorStrategy) OnFailureWithSources(ctx context.Context, locator reference.DockerImageReference) ([]Alternate, error) {
+	return s.resolveInNamespace(ctx, "", locator)
+}
+
+// onFailure returns the alternates of locator for an object in namespace, or for no
+// particular namespace when it is empty.
+func (s *OnErrorStrategy) onFailure(ctx context.Context, namespace string, locator reference.DockerImageReference) ([]reference.DockerImageReference, error) {
+	alternates, err := s.resolveShared(ctx, namespace, locator)
+	if err != nil {
+		return nil, err
+	}
+	if s.minimal && len(alternates) == 1 {
+		return s.singleRef(alternates[0].Ref), nil
+	}
+	return alternateRefs(alternates), nil
+}
+
+// resolveInNamespace returns a copy of the alternates of locator for an object in
+// namespace, with the policy and source of each.
+func (s *OnErrorStrategy) resolveInNamespace(ctx context.Context, namespace string, locator reference.DockerImageReference) ([]Alternate, error) {
+	alternates, err := s.resolveShared(ctx, namespace, locator)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Alternate(nil), alternates...), nil
+}
+
+// resolveShared returns the alternates of locator for an object in namespace, which may
+// be shared with the cache and must not be modified.
+func (s *OnErrorStrategy) resolveShared(ctx context.Context, namespace string, locator reference.DockerImageReference) ([]Alternate, error) {
+	if err := validateLocator(locator); err != nil {
+		return nil, err
+	}
+	locator = s.pinDigest(ctx, locator)
+	if err := s.checkDigest(locator); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	alternates, err := s.lookup(ctx, namespace, locator)
+	if err != nil {
+		return nil, err
+	}
+	if s.tagDigests != nil && len(locator.Tag) > 0 && len(locator.ID) > 0 {
+		s.tagDigests.add(locator)
+	}
+	if s.adaptive {
+		return s.orderBySuccess(alternates), nil
+	}
+	return alternates, nil
+}
+
+// alternatesKey identifies cached alternates. The namespace is only set for namespaces
+// with overlays, so that every other namespace shares the same alternates.
+type alternatesKey struct {
+	namespace string
+	locator   reference.DockerImageReference
+}
+
+// lookup returns the cached alternates of locator in namespace, resolving them on the
+// first request. The caller must hold s.lock, which is released while the mirrors of an
+// uncached image are probed, routed and pinned.
+func (s *OnErrorStrategy) lookup(ctx context.Context, namespace string, locator reference.DockerImageReference) ([]Alternate, error) {
+	overlays := s.namespaceOverlays[namespace]
+	key := alternatesKey{locator: locator}
+	if len(overlays) > 0 {
+		key.namespace = namespace
+	}
+	if s.resolutions == nil {
+		if alternates, ok := s.alternates[key]; ok {
+			return alternates, nil
+		}
+	}
+
+	started := time.Now()
+	budgetCtx, cancel := s.budgetContext(ctx)
+	defer cancel()
+	icspList, err := s.loadICSPs(budgetCtx)
+	if err != nil && s.overBudget(ctx, budgetCtx) {
+		s.budgetWarning(locator.Exact(), "loading the policies", "only the image itself will be tried")
+		r := newResolution(locator)
+		s.logResolution(ctx, locator.Exact(), started, r, nil)
+		return r.returned(), nil
+	}
+	if err != nil {
+		s.logResolution(ctx, locator.Exact(), started, nil, err)
+		return nil, err
+	}
+	if s.tagDigests != nil {
+		fingerprint, err := s.fingerprintFor(icspList)
+		if err != nil {
+			return nil, err
+		}
+		s.tagDigests.observe(fingerprint)
+	}
+	for i := range overlays {
+		icspList = overlays[i].Apply(icspList)
+	}
+	var cacheKey resolutionKey
+	if s.resolutions != nil {
+		fingerprint, err := s.fingerprintFor(icspList)
+		if err != nil {
+			return nil, err
+		}
+		cacheKey = resolutionKey{alternatesKey: key, fingerprint: fingerprint}
+		if alternates, ok := s.resolutions.get(cacheKey); ok {
+			return alternates, nil
+		}
+	}
+	r, err := s.resolve(locator, icspList)
+	partial := false
+	if err == nil {
+		// The mirrors are checked over the network without holding the lock, so that
+		// concurrent resolutions of other images are not serialized behind them.
+		s.lock.Unlock()
+		s.probeMirrors(budgetCtx, r)
+		warning := s.routeMirrors(budgetCtx, r)
+		s.canonicalizeDigests(budgetCtx, r)
+		s.lock.Lock()
+		if len(warning) > 0 {
+			s.warn(warning)
+		}
+		if partial = s.overBudget(ctx, budgetCtx); partial {
+			s.budgetWarning(locator.Exact(), "checking its mirrors", "the mirrors that were not checked keep their order")
+		}
+	}
+	s.logResolution(ctx, locator.Exact(), started, r, err)
+	if err != nil {
+		return nil, err
+	}
+	alternates := r.returned()
+	if id := CorrelationID(ctx); len(id) > 0 {
+		klog.V(4).Infof("[%s] Found alternate sources for %s: %v", id, locator.Exact(), r.refs())
+	} else {
+		klog.V(4).Infof("Found alternate sources for %s: %v", locator.Exact(), r.refs())
+	}
+	s.metrics.record(r)
+	if partial {
+		return alternates, nil
+	}
+	if s.resolutions != nil {
+		s.resolutions.add(cacheKey, alternates)
+	} else {
+		s.alternates[key] = alternates
+	}
+	return alternates, nil
+}
+
+// ResolveForDigest returns the alternates of base pinned to dgst instead of the tag or
+// digest of base. It is intended for the per-architecture manifests of a manifest list,
+// which live in the same repositories as the list and so inherit its mirrors.
+func (s *OnErrorStrategy) ResolveForDigest(ctx context.Context, base reference.DockerImageReference, dgst string) ([]reference.DockerImageReference, error) {
+	if _, err := digest.Parse(dgst); err != nil {
+		return nil, fmt.Errorf("invalid digest %q: %v", dgst, err)
+	}
+	alternates, err := s.OnFailure(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make([]reference.DockerImageReference, 0, len(alternates))
+	for _, alternate := range alternates {
+		alternate.Tag = ""
+		alternate.ID = dgst
+		pinned = append(pinned, alternate)
+	}
+	return pinned, nil
+}
+
+// BestMirror returns the highest ranked mirror of imageRef under the configured ordering
+// and probes, or imageRef itself when it has no mirrors.
+func (s *OnErrorStrategy) BestMirror(ctx context.Context, imageRef reference.DockerImageReference) (reference.DockerImageReference, error) {
+	alternates, err := s.OnFailure(ctx, imageRef)
+	if err != nil {
+		return reference.DockerImageReference{}, err
+	}
+	for _, alternate := range alternates {
+		if alternate.AsRepository() != imageRef.AsRepository() {
+			return alternate, nil
+		}
+	}
+	if len(alternates) == 0 {
+		return reference.DockerImageReference{}, fmt.Errorf("image %s is on a blocked registry and has no mirrors", imageRef.Exact())
+	}
+	return imageRef, nil
+}
+
+// resolve computes the alternates of locator from icspList and applies the configured
+// ordering and checks to them. The caller must hold s.lock.
+func (s *OnErrorStrategy) resolve(locator reference.DockerImageReference, icspList []operatorv1alpha1.ImageContentSourcePolicy) (*resolution, error) {
+	r, err := s.resolveOverride(locator)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil && s.digestOnly && len(locator.ID) == 0 {
+		klog.V(4).Infof("Not mirroring %s, only images pulled by digest are mirrored", locator.Exact())
+		r = newResolution(locator)
+	}
+	if r == nil {
+		idx, err := s.indexFor(icspList)
+		if err != nil {
+			return nil, err
+		}
+		r, err = resolveAlternates(s.aliasRef(locator), idx, s.parse)
+		if err != nil {
+			return nil, err
+		}
+		r.alternates[0].Ref = locator
+		if err := s.applyFallback(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.rewriteMirrors(r); err != nil {
+		return nil, err
+	}
+	if s.sortMirrors {
+		r.sortMirrors()
+	}
+	if s.shuffleMirrors {
+		r.shuffleMirrors(s.shuffleSeed)
+	}
+	if s.preferSameTLD {
+		if tld := topLevelDomain(locator.Registry); len(tld) > 0 {
+			r.promoteMirrors(func(alternate Alternate) bool {
+				return topLevelDomain(alternate.Ref.Registry) == tld
+			})
+		}
+	}
+	if len(s.homeRegistry) > 0 {
+		r.promoteMirrors(func(alternate Alternate) bool {
+			return isSameOrSubdomain(registryHost(alternate.Ref.Registry), s.homeRegistry)
+		})
+	}
+	if len(s.allowedMirrors) > 0 || len(s.deniedMirrors) > 0 {
+		r.filterMirrors(s.mirrorAllowed, SkipFiltered)
+	}
+	if len(s.importRegistries) > 0 {
+		r.filterMirrors(s.allowedForImport, SkipFiltered)
+	}
+	s.blockRegistries(r)
+	if err := s.checkMinimumMirrors(r); err != nil {
+		return nil, err
+	}
+	if err := s.checkPublicMirrors(r); err != nil {
+		return nil, err
+	}
+	s.limitSourceMirrors(r)
+	s.avoidSource(r)
+	s.markInsecureMirrors(r)
+	r.markRegistryMirrors(qualifiedRef(s.aliasRef(locator)))
+	return r, nil
+}
+
+// Init loads and validates the policies from every source, so that a command can report
+// an invalid policy before starting any work. Later requests use the policies loaded by
+// Init rather than reading them again. Init does nothing for a snapshot.
+func (s *OnErrorStrategy) Init(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.snapshot {
+		return nil
+	}
+	s.initialized = false
+	s.preloadedIndex = nil
+	s.preloadedFingerprint = ""
+	if s.tagDigests != nil {
+		s.tagDigests.observe("")
+	}
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return err
+	}
+	if err := validatePolicies(icspList, s.parse); err != nil {
+		return err
+	}
+	if err := s.loadOverrides(); err != nil {
+		return err
+	}
+	idx, err := buildIndex(icspList)
+	if err != nil {
+		return err
+	}
+	s.preloaded = icspList
+	s.preloadedIndex = idx
+	s.initialized = true
+	return nil
+}
+
+// loadICSPs reads the policies from the configured sources in order, falling back to the
+// cluster when no source was provided, orders them by age and kind if requested, then
+// applies any overlays and normalizes them. The policies loaded by Init are returned when
+// it has been called.
+func (s *OnErrorStrategy) loadICSPs(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	if s.initialized {
+		return s.preloaded, nil
+	}
+	icspList, err := s.readICSPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if s.newestFirst {
+		icspList = orderByCreation(icspList)
+	}
+	if len(s.kindPrecedence) > 0 {
+		icspList = orderByKind(icspList, s.kindPrecedence)
+	}
+	if s.digestOnly {
+		icspList = digestPolicies(icspList)
+	}
+	icspList = s.requireAnnotations(icspList)
+	icspList = s.requireOwners(icspList)
+	for _, overlayFn := range s.overlays {
+		overlay, err := overlayFn()
+		if err != nil {
+			return nil, err
+		}
+		icspList = overlay.Apply(icspList)
+	}
+	if len(s.hostAliases) > 0 {
+		icspList = s.aliasPolicies(icspList)
+	}
+	icspList, warnings := normalizePolicies(icspList)
+	for _, warning := range warnings {
+		s.warn(warning)
+	}
+	return icspList, nil
+}
+
+// readICSPs returns the policies as they were loaded from their sources.
+func (s *OnErrorStrategy) readICSPs(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	var icspList []operatorv1alpha1.ImageContentSourcePolicy
+	for _, source := range s.sources {
+		loaded, err := source(ctx)
+		if err != nil {
+			return nil, err
+		}
+		icspList = append(icspList, loaded...)
+	}
+	if len(s.sources) > 0 && !s.mergeCluster {
+		return icspList, nil
+	}
+	if s.icspClient == nil {
+		return icspList, nil
+	}
+	list, err := s.icspClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list ImageContentSourcePolicies: %v", err)
+	}
+	return append(icspList, list.Items...), nil
+}
+
+// warn reports message once for the lifetime of the strategy.
+func (s *OnErrorStrategy) warn(message string) {
+	if s.warned[message] {
+		return
+	}
+	s.warned[message] = true
+	if s.warnings == nil {
+		klog.Warning(message)
+		return
+	}
+	fmt.Fprintf(s.warnings, "warning: %s\n", message)
+}
+
+// readICSPsFromFile reads every ImageContentSourcePolicy document in the named file.
+func readICSPsFromFile(icspFile string, opts decodeOptions) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	data, err := readFileLimited(icspFile, opts.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ImageContentSourcePolicy file %s: %v", icspFile, err)
+	}
+	icspList, err := parseICSPs(data, opts)
+	if invalid, ok := err.(*ValidationError); ok {
+		invalid.File = icspFile
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse ImageContentSourcePolicy file %s: %v", icspFile, err)
+	}
+	return icspList, nil
+}
+
+// parseICSPs decodes a stream of one or more YAML or JSON ImageContentSourcePolicy,
+// ImageDigestMirrorSet or ImageTagMirrorSet documents. Documents of other kinds are an
+// error unless opts ignores them. If opts parses mirrors, an invalid document is reported
+// as a *ValidationError.
+func parseICSPs(data []byte, opts decodeOptions) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	var icspList []operatorv1alpha1.ImageContentSourcePolicy
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		if err := checkDocumentCount(i+1, opts); err != nil {
+			return nil, err
+		}
+		icsp, err := decodePolicy(doc, opts)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+		if icsp == nil {
+			if opts.skipOtherKinds {
+				continue
+			}
+			var typeMeta metav1.TypeMeta
+			yaml.Unmarshal(doc, &typeMeta)
+			return nil, fmt.Errorf("document %d: expected kind ImageContentSourcePolicy, ImageDigestMirrorSet or ImageTagMirrorSet, got %q", i, typeMeta.Kind)
+		}
+		if opts.parse != nil {
+			if invalid := validatePolicy(icsp, opts.parse); invalid != nil {
+				invalid.Document = i
+				return nil, invalid
+			}
+		}
+		icspList = append(icspList, *icsp)
+	}
+	return icspList, nil
+}
+
+// NeverMirror may be listed as the mirror of a source to exclude it, and any repository
+// beneath it, from mirroring. Entries for broader sources no longer apply to it, while
+// entries for more specific sources still do.
+const NeverMirror = "-"
+
+// isExcluded returns true if rdm opts its source out of mirroring.
+func isExcluded(rdm operatorv1alpha1.RepositoryDigestMirrors) bool {
+	for _, mirror := range rdm.Mirrors {
+		if strings.TrimSpace(mirror) == NeverMirror {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeRepository strips the trailing slashes users sometimes leave on sources and
+// mirrors so that they neither prevent a match nor produce doubled slashes when rewritten.
+func normalizeRepository(repository string) string {
+	return strings.TrimRight(strings.TrimSpace(repository), "/")
+}
+
+// matchesSource returns the remainder of repository below source, and whether repository
+// is the source itself or nested beneath it.
+func matchesSource(repository, source string) (string, bool) {
+	if repository == source {
+		return "", true
+	}
+	if strings.HasPrefix(repository, source+"/") {
+		return repository[len(source):], true
+	}
+	return "", false
+}
+
+// alternativeImageSources returns imageRef followed by the unique list of mirrors for it
+// found in icspList.
+func alternativeImageSources(imageRef reference.DockerImageReference, icspList []operatorv1alpha1.ImageContentSourcePolicy) ([]reference.DockerImageReference, error) {
+	idx, err := buildIndex(icspList)
+	if err != nil {
+		return nil, err
+	}
+	r, err := resolveAlternates(imageRef, idx, ParseReference)
+	if err != nil {
+		return nil, err
+	}
+	return r.refs(), nil
+}
+
+// Alternate is a location an image may be retrieved from and the policy entry that
+// declared it.
+type Alternate struct {
+	Ref reference.DockerImageReference
+	// Policy is the name of the policy declaring the mirror, empty for the requested image.
+	Policy string
+	// Source is the normalized source the requested image matched, empty for the requested
+	// image.
+	Source string
+	// Insecure is set on mirrors on hosts marked with WithInsecureMirrors, which may be
+	// reached over plain HTTP or without verifying their certificate.
+	Insecure bool
+	// RegistryRef is the registry of a mirror declared by a registry-level entry, whose
+	// source and mirror are both registries without a repository path, with no repository
+	// set, for callers that enumerate the catalog of the mirror. It is empty otherwise.
+	RegistryRef reference.DockerImageReference
+}
+
+// MatchedEntry is a policy entry that contributed mirrors for the requested image, as it
+// appears in the loaded policy.
+type MatchedEntry struct {
+	Policy string
+	Entry  operatorv1alpha1.RepositoryDigestMirrors
+}
+
+// MatchedPolicy identifies a policy with a source matching the requested image.
+type MatchedPolicy struct {
+	Name       string
+	Generation int64
+}
+
+// SkipReason describes why a matching source or mirror was not used.
+type SkipReason string
+
+const (
+	// SkipExcluded is a source covering an image excluded with NeverMirror.
+	SkipExcluded SkipReason = "Excluded"
+	// SkipTagCondition is a source whose tag conditions the image does not satisfy.
+	SkipTagCondition SkipReason = "TagConditionNotMet"
+	// SkipDuplicate is a mirror already provided by an earlier entry or mirror group.
+	SkipDuplicate SkipReason = "Duplicate"
+	// SkipFiltered is a mirror removed by an allowed or denied registry list.
+	SkipFiltered SkipReason = "Filtered"
+	// SkipBlocked is an image or mirror on a blocked registry.
+	SkipBlocked SkipReason = "Blocked"
+	// SkipUnreachable is a requested image that could not be reached when probed.
+	SkipUnreachable SkipReason = "Unreachable"
+	// SkipLimited is a mirror beyond the limit set for its source.
+	SkipLimited SkipReason = "Limited"
+	// SkipWebhook is a mirror the resolution webhook left out.
+	SkipWebhook SkipReason = "RemovedByWebhook"
+	// SkipNeverContactSource is the requested image when a matching source is declared
+	// NeverContactSource.
+	SkipNeverContactSource SkipReason = "NeverContactSource"
+)
+
+// Skipped is a matching source, or one of its mirrors, that was not used.
+type Skipped struct {
+	// Ref is the skipped mirror, or empty when the whole source was skipped.
+	Ref    reference.DockerImageReference
+	Policy string
+	Source string
+	Reason SkipReason
+}
+
+// resolution is the result of resolving the alternates of one image.
+type resolution struct {
+	alternates []Alternate
+	matched    []MatchedPolicy
+	skipped    []Skipped
+	// sourceMatched is set when any source matched the image, even if it was skipped.
+	sourceMatched bool
+	// sources are the matching sources that contributed mirrors, in policy order.
+	sources []policyEntry
+	// entries are the policy entries of sources.
+	entries []MatchedEntry
+	// sourceOmitted is set when the requested image is on a blocked registry, could not be
+	// reached or must not be contacted, and so is not returned with its mirrors.
+	sourceOmitted bool
+	// neverContactSource is set when a source that contributed mirrors is declared
+	// NeverContactSource, and sourceLast when the requested image is nevertheless returned
+	// after its mirrors.
+	neverContactSource bool
+	sourceLast         bool
+	// source backs alternates until a mirror is added, so that resolving an image without
+	// mirrors does not allocate alternates separately.
+	source [1]Alternate
+}
+
+// newResolution returns a resolution holding only imageRef.
+func newResolution(imageRef reference.DockerImageReference) *resolution {
+	r := &resolution{}
+	r.source[0] = Alternate{Ref: imageRef}
+	r.alternates = r.source[:1:1]
+	return r
+}
+
+// policyEntry identifies a source within a policy.
+type policyEntry struct {
+	policy string
+	source string
+}
+
+// returned is the alternates that are offered to callers.
+func (r *resolution) returned() []Alternate {
+	if r.sourceOmitted {
+		return r.alternates[1:]
+	}
+	if r.sourceLast {
+		returned := make([]Alternate, 0, len(r.alternates))
+		return append(append(returned, r.alternates[1:]...), r.alternates[0])
+	}
+	return r.alternates
+}
+
+func (r *resolution) refs() []reference.DockerImageReference {
+	return alternateRefs(r.returned())
+}
+
+// alternateRefs returns the references of alternates.
+func alternateRefs(alternates []Alternate) []reference.DockerImageReference {
+	refs := make([]reference.DockerImageReference, 0, len(alternates))
+	for _, alternate := range alternates {
+		refs = append(refs, alternate.Ref)
+	}
+	return refs
+}
+
+// qualifiedRef returns imageRef with the registry and namespace that a reference without
+// them implies on Docker Hub, keeping its tag and digest.
+func qualifiedRef(imageRef reference.DockerImageReference) reference.DockerImageReference {
+	qualified := imageRef.DockerClientDefaults()
+	qualified.Tag, qualified.ID = imageRef.Tag, imageRef.ID
+	return qualified
+}
+
+// resolveAlternates returns imageRef followed by the unique list of mirrors for it found
+// in idx. Each mirror carries the tag and digest of imageRef. Sources with tag conditions
+// only contribute mirrors when the tag of imageRef satisfies them, sources at or above an
+// entry excluded with NeverMirror contribute none, and mirrors in the same mirror group
+// are reduced to their first member. An image under a redirected source is matched as its
+// redirected location, which follows imageRef. An image without a registry, such as
+// busybox, is matched as the Docker Hub repository it refers to, docker.io/library/busybox,
+// while the first alternate keeps the form it was requested in. Mirrors are parsed with
+// parse.
+func resolveAlternates(imageRef reference.DockerImageReference, idx *policyIndex, parse ReferenceParser) (*resolution, error) {
+	qualified := qualifiedRef(imageRef)
+	repository := qualified.AsRepository().Exact()
+	r := newResolution(imageRef)
+	// seen holds the mirrors found so far, and is only allocated once there is one since
+	// most images have none.
+	imageKey := equivalenceKey(qualified, idx.groups)
+	var seen map[reference.DockerImageReference]bool
+	markSeen := func(ref reference.DockerImageReference) {
+		if seen == nil {
+			seen = make(map[reference.DockerImageReference]bool)
+		}
+		seen[equivalenceKey(ref, idx.groups)] = true
+	}
+	if redirect := idx.findRedirect(repository); redirect != nil {
+		redirectedRef, err := parse(redirect.target + redirect.suffix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect %q for source %q in ImageContentSourcePolicy %s: %v", redirect.target, redirect.source, redirect.policy, err)
+		}
+		redirectedRef.Tag = imageRef.Tag
+		redirectedRef.ID = imageRef.ID
+		klog.V(4).Infof("Redirecting %s to %s", imageRef.Exact(), redirectedRef.Exact())
+		r.sourceMatched = true
+		r.alternates = append(r.alternates, Alternate{Ref: redirectedRef, Policy: redirect.policy, Source: redirect.source})
+		markSeen(redirectedRef)
+		repository = redirectedRef.AsRepository().Exact()
+	}
+	excluded := idx.excludedSource(repository)
+	for i := range idx.policies {
+		policy := &idx.policies[i]
+		matched := false
+		for j := range policy.entries {
+			entry := &policy.entries[j]
+			source := entry.source
+			suffix, ok := matchesSource(repository, source)
+			if !ok {
+				continue
+			}
+			r.sourceMatched = true
+			if len(excluded) > 0 && len(source) <= len(excluded) {
+				klog.V(4).Infof("Skipping mirrors of %s for %s, %s is excluded from mirroring", source, imageRef.Exact(), excluded)
+				r.skipped = append(r.skipped, Skipped{Policy: policy.name, Source: source, Reason: SkipExcluded})
+				continue
+			}
+			if !tagMatches(imageRef.Tag, entry.conditions) {
+				klog.V(4).Infof("Skipping mirrors of %s for %s, tag %q does not match %v", source, imageRef.Exact(), imageRef.Tag, entry.conditions)
+				r.skipped = append(r.skipped, Skipped{Policy: policy.name, Source: source, Reason: SkipTagCondition})
+				continue
+			}
+			matched = true
+			if entry.neverContactSource {
+				r.neverContactSource = true
+			}
+			r.sources = append(r.sources, policyEntry{policy: policy.name, source: source})
+			r.entries = append(r.entries, MatchedEntry{Policy: policy.name, Entry: *entry.rdm.DeepCopy()})
+			for k, mirror := range entry.mirrors {
+				mirrorRef, err := parse(mirror + suffix)
+				if err != nil {
+					return nil, fmt.Errorf("invalid mirror %q for source %q in ImageContentSourcePolicy %s: %v", entry.rdm.Mirrors[k], entry.rdm.Source, policy.name, err)
+				}
+				mirrorRef.Tag = imageRef.Tag
+				mirrorRef.ID = imageRef.ID
+				if key := equivalenceKey(mirrorRef, idx.groups); key == imageKey || seen[key] {
+					r.skipped = append(r.skipped, Skipped{Ref: mirrorRef, Policy: policy.name, Source: source, Reason: SkipDuplicate})
+					continue
+				}
+				markSeen(mirrorRef)
+				r.alternates = append(r.alternates, Alternate{Ref: mirrorRef, Policy: policy.name, Source: source})
+			}
+		}
+		if matched {
+			r.matched = append(r.matched, MatchedPolicy{Name: policy.name, Generation: policy.generation})
+		}
+	}
+	return r, nil
+}
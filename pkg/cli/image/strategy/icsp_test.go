@@ -0,0 +1,596 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+const testDigest = "sha256:d134a9865524c29fcf75bbc4469013bc38d8a15cb5f41acfddb6b9e492f556e4"
+
+type fakeICSPLister struct {
+	items []operatorv1alpha1.ImageContentSourcePolicy
+	err   error
+	calls int
+}
+
+func (f *fakeICSPLister) List(ctx context.Context, opts metav1.ListOptions) (*operatorv1alpha1.ImageContentSourcePolicyList, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &operatorv1alpha1.ImageContentSourcePolicyList{Items: f.items}, nil
+}
+
+func newICSP(name string, rdms ...operatorv1alpha1.RepositoryDigestMirrors) operatorv1alpha1.ImageContentSourcePolicy {
+	return operatorv1alpha1.ImageContentSourcePolicy{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "operator.openshift.io/v1alpha1", Kind: "ImageContentSourcePolicy"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       operatorv1alpha1.ImageContentSourcePolicySpec{RepositoryDigestMirrors: rdms},
+	}
+}
+
+func rdm(source string, mirrors ...string) operatorv1alpha1.RepositoryDigestMirrors {
+	return operatorv1alpha1.RepositoryDigestMirrors{Source: source, Mirrors: mirrors}
+}
+
+func mustParse(t *testing.T, spec string) reference.DockerImageReference {
+	t.Helper()
+	ref, err := reference.Parse(spec)
+	if err != nil {
+		t.Fatalf("unable to parse %q: %v", spec, err)
+	}
+	return ref
+}
+
+func exactRefs(refs []reference.DockerImageReference) []string {
+	var out []string
+	for _, ref := range refs {
+		out = append(out, ref.Exact())
+	}
+	return out
+}
+
+func TestAlternativeImageSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		icspList []operatorv1alpha1.ImageContentSourcePolicy
+		image    string
+		expected []string
+	}{
+		{
+			name:     "no policies",
+			image:    "quay.io/ocp-test/release@" + testDigest,
+			expected: []string{"quay.io/ocp-test/release@" + testDigest},
+		},
+		{
+			name: "exact repository match",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp-test/release", "registry.example.com/ocp-test/release", "mirror.example.com/release")),
+			},
+			image: "quay.io/ocp-test/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/release@" + testDigest,
+				"registry.example.com/ocp-test/release@" + testDigest,
+				"mirror.example.com/release@" + testDigest,
+			},
+		},
+		{
+			name: "namespace source matches nested repository",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp-test", "registry.example.com/mirrored")),
+			},
+			image: "quay.io/ocp-test/release:4.5",
+			expected: []string{
+				"quay.io/ocp-test/release:4.5",
+				"registry.example.com/mirrored/release:4.5",
+			},
+		},
+		{
+			name: "source prefix does not match partial path segment",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp", "registry.example.com/ocp")),
+			},
+			image:    "quay.io/ocp-test/release:4.5",
+			expected: []string{"quay.io/ocp-test/release:4.5"},
+		},
+		{
+			name: "duplicate mirrors across policies are merged in order",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp-test/release", "registry.example.com/release", "mirror.example.com/release")),
+				newICSP("b", rdm("quay.io/ocp-test/release", "mirror.example.com/release", "other.example.com/release")),
+			},
+			image: "quay.io/ocp-test/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/release@" + testDigest,
+				"registry.example.com/release@" + testDigest,
+				"mirror.example.com/release@" + testDigest,
+				"other.example.com/release@" + testDigest,
+			},
+		},
+		{
+			name: "trailing slash on source",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp-test/", "registry.example.com/ocp-test")),
+			},
+			image: "quay.io/ocp-test/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/release@" + testDigest,
+				"registry.example.com/ocp-test/release@" + testDigest,
+			},
+		},
+		{
+			name: "trailing slash on mirror",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp-test", "registry.example.com/ocp-test/")),
+			},
+			image: "quay.io/ocp-test/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/release@" + testDigest,
+				"registry.example.com/ocp-test/release@" + testDigest,
+			},
+		},
+		{
+			name: "trailing slashes on exact source and mirror",
+			icspList: []operatorv1alpha1.ImageContentSourcePolicy{
+				newICSP("a", rdm("quay.io/ocp-test/release//", "registry.example.com/ocp-test/release/")),
+			},
+			image: "quay.io/ocp-test/release:4.5",
+			expected: []string{
+				"quay.io/ocp-test/release:4.5",
+				"registry.example.com/ocp-test/release:4.5",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alternates, err := alternativeImageSources(mustParse(t, tt.image), tt.icspList)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOnFailureFromFile(t *testing.T) {
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml")
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp-test/release@"+testDigest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp-test/release@" + testDigest,
+		"registry.example.com/ocp-test/release@" + testDigest,
+		"mirror.example.com/ocp-test/release@" + testDigest,
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestOnFailureFromClient(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("a", rdm("registry.redhat.io/operators", "registry.example.com/operators")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	image := mustParse(t, "registry.redhat.io/operators/etcd@"+testDigest)
+	for i := 0; i < 2; i++ {
+		alternates, err := s.OnFailure(context.Background(), image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{
+			"registry.redhat.io/operators/etcd@" + testDigest,
+			"registry.example.com/operators/etcd@" + testDigest,
+		}
+		if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("expected alternates to be cached after the first lookup, listed %d times", client.calls)
+	}
+}
+
+func TestOnFailureInlinePolicy(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/icsp.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromFile := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml")
+	inline := NewICSPOnErrorStrategy(nil, "", WithInlinePolicy(string(data)))
+	for _, image := range []string{
+		"quay.io/ocp-test/release@" + testDigest,
+		"quay.io/ocp-test/other:4.5",
+		"registry.redhat.io/operators/etcd@" + testDigest,
+		"docker.io/library/busybox:latest",
+	} {
+		ref := mustParse(t, image)
+		expected, err := fromFile.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := inline.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", image, exactRefs(expected), exactRefs(got))
+		}
+	}
+
+	invalid := NewICSPOnErrorStrategy(nil, "", WithInlinePolicy("kind: Pod\n"))
+	if _, err := invalid.OnFailure(context.Background(), mustParse(t, "quay.io/ocp-test/release:4.5")); err == nil {
+		t.Errorf("expected an error for an inline policy of the wrong kind")
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	image := mustParse(t, "quay.io/ocp-test/release@"+testDigest)
+
+	lenient := NewICSPOnErrorStrategy(nil, "testdata/icsp-misspelled.yaml")
+	alternates, err := lenient.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error in lenient mode: %v", err)
+	}
+	if got, expected := exactRefs(alternates), []string{image.Exact()}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	strict := NewICSPOnErrorStrategy(nil, "testdata/icsp-misspelled.yaml", WithStrictDecoding())
+	if _, err := strict.OnFailure(context.Background(), image); err == nil || !strings.Contains(err.Error(), `unknown field "mirror"`) {
+		t.Errorf("expected an unknown field error in strict mode, got %v", err)
+	}
+
+	valid := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithStrictDecoding())
+	if _, err := valid.OnFailure(context.Background(), image); err != nil {
+		t.Errorf("unexpected error for a valid file in strict mode: %v", err)
+	}
+}
+
+func TestWithOtherKindsIgnored(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/kustomize.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseICSPs(data, decodeOptions{}); err == nil || !strings.Contains(err.Error(), `got "Namespace"`) {
+		t.Errorf("expected other kinds to be rejected by default, got %v", err)
+	}
+	icspList, err := parseICSPs(data, decodeOptions{skipOtherKinds: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var loaded []string
+	for i := range icspList {
+		loaded = append(loaded, policyKind(&icspList[i])+"/"+icspList[i].Name)
+	}
+	expected := []string{"ImageDigestMirrorSet/operators", "ImageContentSourcePolicy/release", "ImageTagMirrorSet/tools"}
+	if !reflect.DeepEqual(loaded, expected) {
+		t.Errorf("expected %v, got %v", expected, loaded)
+	}
+	if _, err := parseICSPs(data, decodeOptions{skipOtherKinds: true, maxDocuments: len(icspList)}); err == nil || !strings.Contains(err.Error(), "exceeds the maximum of") {
+		t.Errorf("expected ignored documents to count towards the document limit, got %v", err)
+	}
+
+	s := NewICSPOnErrorStrategy(nil, "testdata/kustomize.yaml", WithOtherKindsIgnored())
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp-test/release@"+testDigest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, expected := exactRefs(alternates), []string{"quay.io/ocp-test/release@" + testDigest, "registry.example.com/ocp-test/release@" + testDigest}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestUnqualifiedImages(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("hub", rdm("docker.io/library/busybox", "registry.example.com/library/busybox")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{image: "busybox", expected: []string{"busybox", "registry.example.com/library/busybox"}},
+		{image: "busybox:1.36", expected: []string{"busybox:1.36", "registry.example.com/library/busybox:1.36"}},
+		{image: "busybox@" + testDigest, expected: []string{"busybox@" + testDigest, "registry.example.com/library/busybox@" + testDigest}},
+		{image: "library/busybox:1.36", expected: []string{"library/busybox:1.36", "registry.example.com/library/busybox:1.36"}},
+		{image: "docker.io/busybox:1.36", expected: []string{"docker.io/busybox:1.36", "registry.example.com/library/busybox:1.36"}},
+		{image: "alpine", expected: []string{"alpine"}},
+		{image: "alpine:3.18", expected: []string{"alpine:3.18"}},
+		{image: "example/busybox:1.36", expected: []string{"example/busybox:1.36"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithPolicyFiles(t *testing.T) {
+	first, second, third := "testdata/merge-first.yaml", "testdata/merge-second.yaml", "testdata/merge-third.yaml"
+	mirrors := func(registries ...string) []string {
+		refs := []string{"quay.io/ocp/release@" + testDigest}
+		for _, registry := range registries {
+			refs = append(refs, registry+"/ocp/release@"+testDigest)
+		}
+		return refs
+	}
+	tests := []struct {
+		name     string
+		icspFile string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "load order",
+			opts:     []Option{WithPolicyFiles(first, second, third)},
+			expected: mirrors("a.example.com", "b.example.com", "c.example.com", "d.example.com", "e.example.com"),
+		},
+		{
+			name:     "reversed",
+			opts:     []Option{WithPolicyFiles(third, second, first)},
+			expected: mirrors("d.example.com", "e.example.com", "c.example.com", "a.example.com", "b.example.com"),
+		},
+		{
+			name:     "constructor file first",
+			icspFile: second,
+			opts:     []Option{WithPolicyFiles(third), WithPolicyFiles(first)},
+			expected: mirrors("c.example.com", "a.example.com", "b.example.com", "d.example.com", "e.example.com"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				s := NewICSPOnErrorStrategy(nil, tt.icspFile, tt.opts...)
+				alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release@"+testDigest))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveForDigest(t *testing.T) {
+	const archDigest = "sha256:4d2ba2cb0ef0cd7aa0bfe6e3a0d2ef11db2a4b8fc3e0aa5bd94b1ec064f17ea7"
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml")
+	alternates, err := s.ResolveForDigest(context.Background(), mustParse(t, "quay.io/ocp-test/release:4.5"), archDigest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp-test/release@" + archDigest,
+		"registry.example.com/ocp-test/release@" + archDigest,
+		"mirror.example.com/ocp-test/release@" + archDigest,
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := s.ResolveForDigest(context.Background(), mustParse(t, "quay.io/ocp-test/release:4.5"), "not-a-digest"); err == nil {
+		t.Errorf("expected an error for an invalid digest")
+	}
+}
+
+func TestBestMirror(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release",
+			"registry.example.com/ocp/release",
+			"cache.dc1.example.com/ocp/release",
+			"backup.example.com/ocp/release",
+		)),
+	}}
+	unreachable := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		if ref.Registry != "backup.example.com" {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	})
+	tests := []struct {
+		name     string
+		image    string
+		opts     []Option
+		expected string
+	}{
+		{name: "policy order", image: "quay.io/ocp/release:4.8", expected: "registry.example.com/ocp/release:4.8"},
+		{name: "sorted", image: "quay.io/ocp/release:4.8", opts: []Option{WithSortedMirrors()}, expected: "backup.example.com/ocp/release:4.8"},
+		{name: "home registry", image: "quay.io/ocp/release:4.8", opts: []Option{WithHomeRegistry("dc1.example.com")}, expected: "cache.dc1.example.com/ocp/release:4.8"},
+		{name: "probe", image: "quay.io/ocp/release:4.8", opts: []Option{WithProbe(unreachable)}, expected: "backup.example.com/ocp/release:4.8"},
+		{name: "blocked source", image: "quay.io/ocp/release:4.8", opts: []Option{WithBlockedRegistries("quay.io")}, expected: "registry.example.com/ocp/release:4.8"},
+		{name: "no mirrors", image: "quay.io/other/image:latest", expected: "quay.io/other/image:latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", append(tt.opts, WithWarnings(ioutil.Discard))...)
+			best, err := s.BestMirror(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if best.Exact() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, best.Exact())
+			}
+		})
+	}
+
+	s := NewICSPOnErrorStrategy(client, "", WithBlockedRegistries("quay.io"), WithWarnings(ioutil.Discard))
+	if _, err := s.BestMirror(context.Background(), mustParse(t, "quay.io/other/image:latest")); err == nil {
+		t.Errorf("expected an error for a blocked image without mirrors")
+	}
+}
+
+func TestNeverMirror(t *testing.T) {
+	icspList := []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("broad", rdm("quay.io/ocp", "registry.example.com/ocp")),
+		newICSP("optout", rdm("quay.io/ocp/private", NeverMirror)),
+		newICSP("specific", rdm("quay.io/ocp/private/allowed", "internal.example.com/allowed")),
+	}
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image:    "quay.io/ocp/release:4.5",
+			expected: []string{"quay.io/ocp/release:4.5", "registry.example.com/ocp/release:4.5"},
+		},
+		{
+			image:    "quay.io/ocp/private:4.5",
+			expected: []string{"quay.io/ocp/private:4.5"},
+		},
+		{
+			image:    "quay.io/ocp/private/secret:4.5",
+			expected: []string{"quay.io/ocp/private/secret:4.5"},
+		},
+		{
+			image:    "quay.io/ocp/private/allowed:4.5",
+			expected: []string{"quay.io/ocp/private/allowed:4.5", "internal.example.com/allowed:4.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := alternativeImageSources(mustParse(t, tt.image), icspList)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	t.Run("parse error", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(nil, "", WithInlinePolicy("kind: ImageContentSourcePolicy\nspec: [\n"))
+		if err := s.Init(context.Background()); err == nil {
+			t.Fatalf("expected Init to report the parse error")
+		}
+	})
+
+	t.Run("invalid mirror", func(t *testing.T) {
+		client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+			newICSP("broken", rdm("quay.io/ocp/release", "Invalid//Mirror")),
+		}}
+		err := NewICSPOnErrorStrategy(client, "").Init(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "Invalid//Mirror") {
+			t.Fatalf("expected Init to report the invalid mirror, got %v", err)
+		}
+	})
+
+	t.Run("preloads", func(t *testing.T) {
+		client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+			newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		}}
+		s := NewICSPOnErrorStrategy(client, "")
+		if err := s.Init(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		client.err = fmt.Errorf("unexpected list")
+		for _, image := range []string{"quay.io/ocp/release:4.8", "quay.io/ocp/release:4.9"} {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(alternates) != 2 {
+				t.Errorf("expected a mirror for %s, got %v", image, exactRefs(alternates))
+			}
+		}
+		if client.calls != 1 {
+			t.Errorf("expected policies to be listed once by Init, got %d", client.calls)
+		}
+	})
+}
+
+func TestTagAndDigest(t *testing.T) {
+	tagged := newICSP("tagged", rdm("quay.io/ocp/tagged", "registry.example.com/ocp/tagged"))
+	tagged.Annotations = map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/tagged": ["4.*"]}`}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		tagged,
+	}}
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image:    "quay.io/ocp/release:4.8@" + testDigest,
+			expected: []string{"quay.io/ocp/release@" + testDigest, "registry.example.com/ocp/release@" + testDigest},
+		},
+		{
+			image:    "quay.io/ocp/tagged:4.8@" + testDigest,
+			expected: []string{"quay.io/ocp/tagged@" + testDigest, "registry.example.com/ocp/tagged@" + testDigest},
+		},
+		{
+			image:    "quay.io/ocp/tagged:latest@" + testDigest,
+			expected: []string{"quay.io/ocp/tagged@" + testDigest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			image := mustParse(t, tt.image)
+			alternates, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			for _, alternate := range alternates {
+				if alternate.Tag != image.Tag || alternate.ID != image.ID {
+					t.Errorf("expected %s to keep tag %q and digest %s", alternate.String(), image.Tag, image.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOnFailureWithSources(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		newICSP("namespace", rdm("quay.io/ocp", "mirror.example.com/ocp")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	expected := []Alternate{
+		{Ref: mustParse(t, "quay.io/ocp/release:4.8")},
+		{Ref: mustParse(t, "registry.example.com/ocp/release:4.8"), Policy: "release", Source: "quay.io/ocp/release"},
+		{Ref: mustParse(t, "mirror.example.com/ocp/release:4.8"), Policy: "namespace", Source: "quay.io/ocp"},
+	}
+	for i := 0; i < 2; i++ {
+		alternates, err := s.OnFailureWithSources(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(alternates, expected) {
+			t.Errorf("expected %#v, got %#v", expected, alternates)
+		}
+		alternates[1].Source = "modified"
+	}
+
+	refs, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(refs, alternateRefs(expected)) {
+		t.Errorf("expected OnFailure to return the same alternates, got %v", exactRefs(refs))
+	}
+}
@@ -0,0 +1,38 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ResolveRepository returns repository followed by its mirrors, all without a tag or digest.
+// Sources restricted to certain tags never match a repository.
+func (s *OnErrorStrategy) ResolveRepository(ctx context.Context, repository string) ([]reference.DockerImageReference, error) {
+	ref, err := s.parse(repository)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository %q: %v", repository, err)
+	}
+	return s.ResolveRepositoryReference(ctx, ref)
+}
+
+// ResolveRepositoryReference is ResolveRepository for a parsed reference, so that callers
+// can enumerate the tags of each returned repository against its registry.
+func (s *OnErrorStrategy) ResolveRepositoryReference(ctx context.Context, ref reference.DockerImageReference) ([]reference.DockerImageReference, error) {
+	if len(ref.Tag) > 0 || len(ref.ID) > 0 {
+		return nil, fmt.Errorf("%q is an image, not a repository", ref.Exact())
+	}
+	return s.OnFailure(ctx, ref)
+}
+
+// ResolveImageStream returns the mirrors of the repository an image stream is served from,
+// as reported in its status.
+func (s *OnErrorStrategy) ResolveImageStream(ctx context.Context, stream *imagev1.ImageStream) ([]reference.DockerImageReference, error) {
+	repository := stream.Status.DockerImageRepository
+	if len(repository) == 0 {
+		return nil, fmt.Errorf("image stream %s/%s has no docker image repository", stream.Namespace, stream.Name)
+	}
+	return s.ResolveRepository(ctx, repository)
+}
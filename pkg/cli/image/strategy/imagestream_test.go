@@ -0,0 +1,94 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResolveRepository(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("internal", rdm("image-registry.openshift-image-registry.svc:5000/apps", "registry.example.com/apps")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	tests := []struct {
+		repository string
+		expected   []string
+		expectErr  bool
+	}{
+		{
+			repository: "image-registry.openshift-image-registry.svc:5000/apps/frontend",
+			expected:   []string{"image-registry.openshift-image-registry.svc:5000/apps/frontend", "registry.example.com/apps/frontend"},
+		},
+		{
+			repository: "image-registry.openshift-image-registry.svc:5000/other/frontend",
+			expected:   []string{"image-registry.openshift-image-registry.svc:5000/other/frontend"},
+		},
+		{repository: "image-registry.openshift-image-registry.svc:5000/apps/frontend:latest", expectErr: true},
+		{repository: "Invalid//Repository", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			alternates, err := s.ResolveRepository(context.Background(), tt.repository)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("expected error %t, got %v", tt.expectErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveImageStream(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("internal", rdm("image-registry.openshift-image-registry.svc:5000/apps", "registry.example.com/apps")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	stream := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "frontend"},
+		Status:     imagev1.ImageStreamStatus{DockerImageRepository: "image-registry.openshift-image-registry.svc:5000/apps/frontend"},
+	}
+	alternates, err := s.ResolveImageStream(context.Background(), stream)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alternates) != 2 || alternates[1].Exact() != "registry.example.com/apps/frontend" {
+		t.Errorf("unexpected alternates %v", exactRefs(alternates))
+	}
+
+	if _, err := s.ResolveImageStream(context.Background(), &imagev1.ImageStream{}); err == nil {
+		t.Errorf("expected an error for an image stream without a repository")
+	}
+}
+
+func TestResolveRepositoryReference(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp", "registry.example.com/ocp", "mirror.example.com/ocp")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	repositories, err := s.ResolveRepositoryReference(context.Background(), mustParse(t, "quay.io/ocp/release"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com/ocp/release"}
+	if actual := exactRefs(repositories); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	for _, repository := range repositories {
+		if len(repository.Tag) > 0 || len(repository.ID) > 0 {
+			t.Errorf("expected %s to have no tag or digest", repository.Exact())
+		}
+	}
+
+	if _, err := s.ResolveRepositoryReference(context.Background(), mustParse(t, "quay.io/ocp/release:4.8")); err == nil {
+		t.Errorf("expected an error for a tagged image")
+	}
+}
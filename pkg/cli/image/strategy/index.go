@@ -0,0 +1,137 @@
+package strategy
+
+import (
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// policyIndex is the form of a set of policies that images are resolved against. Sources
+// and mirrors are normalized and annotations decoded once when the index is built, instead
+// of for every image, so that resolving against very large policies allocates little more
+// than the returned references.
+type policyIndex struct {
+	policies  []indexedPolicy
+	groups    map[string]string
+	redirects []redirect
+	// excluded are the normalized sources excluded from mirroring with NeverMirror.
+	excluded []string
+}
+
+// indexedPolicy is one policy of a policyIndex.
+type indexedPolicy struct {
+	name       string
+	generation int64
+	entries    []indexedEntry
+}
+
+// indexedEntry is one source of an indexedPolicy.
+type indexedEntry struct {
+	source  string
+	mirrors []string
+	// conditions are the tag patterns restricting source, or nil if it is unconditional.
+	conditions []string
+	// neverContactSource is set when the source must only be retrieved from its mirrors.
+	neverContactSource bool
+	// rdm is the entry as declared, which is reported by explanations.
+	rdm *operatorv1alpha1.RepositoryDigestMirrors
+}
+
+// buildIndex returns the index of icspList, which must not be modified while the index is
+// in use. It fails for the annotations that would fail the resolution of any image.
+func buildIndex(icspList []operatorv1alpha1.ImageContentSourcePolicy) (*policyIndex, error) {
+	groups, err := mirrorGroups(icspList)
+	if err != nil {
+		return nil, err
+	}
+	idx := &policyIndex{groups: groups, policies: make([]indexedPolicy, 0, len(icspList))}
+	for i := range icspList {
+		icsp := &icspList[i]
+		declared, err := redirects(icsp)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sortedKeys(declared) {
+			idx.redirects = append(idx.redirects, redirect{policy: icsp.Name, source: source, target: declared[source]})
+		}
+	}
+	for i := range icspList {
+		icsp := &icspList[i]
+		conditions, err := tagPatterns(icsp)
+		if err != nil {
+			return nil, err
+		}
+		neverContact, err := neverContactSources(icsp)
+		if err != nil {
+			return nil, err
+		}
+		policy := indexedPolicy{
+			name:       icsp.Name,
+			generation: icsp.Generation,
+			entries:    make([]indexedEntry, 0, len(icsp.Spec.RepositoryDigestMirrors)),
+		}
+		for j := range icsp.Spec.RepositoryDigestMirrors {
+			rdm := &icsp.Spec.RepositoryDigestMirrors[j]
+			source := normalizeRepository(rdm.Source)
+			if isExcluded(*rdm) {
+				idx.excluded = append(idx.excluded, source)
+			}
+			entry := indexedEntry{
+				source:             source,
+				mirrors:            make([]string, 0, len(rdm.Mirrors)),
+				conditions:         conditions[source],
+				neverContactSource: neverContact[source],
+				rdm:                rdm,
+			}
+			for _, mirror := range rdm.Mirrors {
+				entry.mirrors = append(entry.mirrors, normalizeRepository(mirror))
+			}
+			policy.entries = append(policy.entries, entry)
+		}
+		idx.policies = append(idx.policies, policy)
+	}
+	return idx, nil
+}
+
+// findRedirect returns the redirect with the most specific source covering repository, or
+// nil if no source covering it is redirected. Redirects are not followed transitively.
+func (idx *policyIndex) findRedirect(repository string) *redirect {
+	var found *redirect
+	for _, candidate := range idx.redirects {
+		suffix, ok := matchesSource(repository, candidate.source)
+		if !ok || (found != nil && len(candidate.source) <= len(found.source)) {
+			continue
+		}
+		match := candidate
+		match.suffix = suffix
+		found = &match
+	}
+	return found
+}
+
+// excludedSource returns the most specific excluded source that repository falls under,
+// or an empty string.
+func (idx *policyIndex) excludedSource(repository string) string {
+	var excluded string
+	for _, source := range idx.excluded {
+		if _, ok := matchesSource(repository, source); ok && len(source) > len(excluded) {
+			excluded = source
+		}
+	}
+	return excluded
+}
+
+// indexFor returns the index of icspList, reusing the index built by Init when icspList
+// is the set of policies Init loaded. The caller must hold s.lock.
+func (s *OnErrorStrategy) indexFor(icspList []operatorv1alpha1.ImageContentSourcePolicy) (*policyIndex, error) {
+	if s.preloadedIndex != nil && sameList(icspList, s.preloaded) {
+		return s.preloadedIndex, nil
+	}
+	return buildIndex(icspList)
+}
+
+// sameList returns true if a and b are the same slice.
+func sameList(a, b []operatorv1alpha1.ImageContentSourcePolicy) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	return len(a) == 0 || &a[0] == &b[0]
+}
@@ -0,0 +1,168 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// largePolicies returns count policies that each mirror the same set of sources to hosts
+// shared between policies, with tag conditions on some of them.
+func largePolicies(count int) []operatorv1alpha1.ImageContentSourcePolicy {
+	icspList := make([]operatorv1alpha1.ImageContentSourcePolicy, 0, count)
+	for i := 0; i < count; i++ {
+		var rdms []operatorv1alpha1.RepositoryDigestMirrors
+		for j := 0; j < 10; j++ {
+			source := fmt.Sprintf("quay.io/team-%d/app-%d", j%3, j)
+			rdms = append(rdms, rdm(source,
+				fmt.Sprintf("mirror-%d.example.com/team-%d/app-%d", i%5, j%3, j),
+				fmt.Sprintf("cache.example.com/policy-%d/app-%d", i, j),
+			))
+		}
+		rdms = append(rdms, rdm(fmt.Sprintf("quay.io/team-%d", i%3), fmt.Sprintf("team-%d.example.com/quay", i%3)))
+		icsp := newICSP(fmt.Sprintf("policy-%d", i), rdms...)
+		if i%4 == 0 {
+			icsp.Annotations = map[string]string{TagPatternsAnnotation: `{"quay.io/team-0/app-0": ["v1.*"]}`}
+		}
+		icspList = append(icspList, icsp)
+	}
+	return icspList
+}
+
+// naiveAlternates resolves imageRef against icspList without an index, for policies
+// without exclusions, redirects or mirror groups.
+func naiveAlternates(t *testing.T, imageRef reference.DockerImageReference, icspList []operatorv1alpha1.ImageContentSourcePolicy) []string {
+	repository := imageRef.AsRepository().Exact()
+	alternates := []string{imageRef.Exact()}
+	seen := map[string]bool{imageRef.Exact(): true}
+	for i := range icspList {
+		conditions, err := tagPatterns(&icspList[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, rdm := range icspList[i].Spec.RepositoryDigestMirrors {
+			suffix, ok := matchesSource(repository, rdm.Source)
+			if !ok || !tagMatches(imageRef.Tag, conditions[rdm.Source]) {
+				continue
+			}
+			for _, mirror := range rdm.Mirrors {
+				ref := mustParse(t, mirror+suffix)
+				ref.Tag, ref.ID = imageRef.Tag, imageRef.ID
+				if !seen[ref.Exact()] {
+					seen[ref.Exact()] = true
+					alternates = append(alternates, ref.Exact())
+				}
+			}
+		}
+	}
+	return alternates
+}
+
+func TestPolicyIndexEquivalence(t *testing.T) {
+	icspList := largePolicies(40)
+	idx, err := buildIndex(icspList)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := &fakeICSPLister{items: icspList}
+	initialized := NewICSPOnErrorStrategy(client, "")
+	if err := initialized.Init(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	uninitialized := NewICSPOnErrorStrategy(client, "")
+	images := []string{
+		"quay.io/team-0/app-0:v1.2",
+		"quay.io/team-0/app-0:v2.0",
+		"quay.io/team-1/app-4/nested@" + testDigest,
+		"quay.io/team-2/other:latest",
+		"docker.io/library/busybox:latest",
+	}
+	for _, image := range images {
+		t.Run(image, func(t *testing.T) {
+			ref := mustParse(t, image)
+			expected := naiveAlternates(t, ref, icspList)
+			r, err := resolveAlternates(ref, idx, ParseReference)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(r.refs()); !reflect.DeepEqual(expected, actual) {
+				t.Errorf("expected %v, got %v", expected, actual)
+			}
+			for _, s := range []*OnErrorStrategy{initialized, uninitialized} {
+				alternates, err := s.OnFailure(context.Background(), ref)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+					t.Errorf("expected %v, got %v", expected, actual)
+				}
+			}
+		})
+	}
+	if initialized.preloadedIndex == nil {
+		t.Errorf("expected Init to build the index once")
+	}
+}
+
+func TestBuildIndexInvalidAnnotations(t *testing.T) {
+	icsp := newICSP("invalid", rdm("quay.io/ocp/release", "registry.example.com/ocp/release"))
+	icsp.Annotations = map[string]string{RedirectsAnnotation: "not json"}
+	if _, err := buildIndex([]operatorv1alpha1.ImageContentSourcePolicy{icsp}); err == nil || !strings.Contains(err.Error(), RedirectsAnnotation) {
+		t.Errorf("expected an error for the invalid annotation, got %v", err)
+	}
+}
+
+func BenchmarkResolveAlternates(b *testing.B) {
+	icspList := largePolicies(1000)
+	ref, err := ParseReference("quay.io/team-1/app-4:v1.0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("index reused", func(b *testing.B) {
+		idx, err := buildIndex(icspList)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := resolveAlternates(ref, idx, ParseReference); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("no match", func(b *testing.B) {
+		idx, err := buildIndex(icspList)
+		if err != nil {
+			b.Fatal(err)
+		}
+		unmatched, err := ParseReference("registry.example.com/other/app:v1.0")
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := resolveAlternates(unmatched, idx, ParseReference); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("index rebuilt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx, err := buildIndex(icspList)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := resolveAlternates(ref, idx, ParseReference); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package strategy
+
+import (
+	"strings"
+)
+
+// WithInsecureMirrors marks the mirrors on the given hosts as insecure, which callers may
+// then reach over plain HTTP or without verifying their certificate regardless of the
+// cluster configuration. A host without a port matches every port of the host, while a
+// host with a port only matches that port. The requested image is never marked.
+func WithInsecureMirrors(hosts ...string) Option {
+	return func(s *OnErrorStrategy) {
+		for _, host := range hosts {
+			s.insecureMirrors = append(s.insecureMirrors, strings.ToLower(strings.TrimSpace(host)))
+		}
+	}
+}
+
+// isInsecureMirror returns true if registry is on one of the insecure mirror hosts, or
+// is marked insecure by a registries.conf file.
+func (s *OnErrorStrategy) isInsecureMirror(registry string) bool {
+	registry = strings.ToLower(registry)
+	if matchesInsecureHost(registry, s.insecureMirrors) {
+		return true
+	}
+	for _, hosts := range s.insecureConf {
+		if matchesInsecureHost(registry, hosts) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesInsecureHost returns true if registry is on one of hosts.
+func matchesInsecureHost(registry string, hosts []string) bool {
+	for _, host := range hosts {
+		if registry == host || registryHost(registry) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// markInsecureMirrors flags the mirrors of r on insecure hosts.
+func (s *OnErrorStrategy) markInsecureMirrors(r *resolution) {
+	if len(s.insecureMirrors) == 0 && len(s.insecureConf) == 0 {
+		return
+	}
+	for i := 1; i < len(r.alternates); i++ {
+		r.alternates[i].Insecure = s.isInsecureMirror(r.alternates[i].Ref.Registry)
+	}
+}
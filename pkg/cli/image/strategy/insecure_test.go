@@ -0,0 +1,54 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithInsecureMirrors(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release",
+			"registry.example.com/ocp/release",
+			"registry.example.com:5000/ocp/release",
+			"other.example.com:5000/ocp/release",
+			"other.example.com:5001/ocp/release",
+			"secure.example.com/ocp/release",
+			"quay.io/mirror/release",
+		)),
+	}}
+	s := NewICSPOnErrorStrategy(client, "", WithInsecureMirrors("Registry.Example.com", "other.example.com:5000", "quay.io"))
+	alternates, err := s.OnFailureWithSources(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{
+		"quay.io/ocp/release:4.8":                   false,
+		"registry.example.com/ocp/release:4.8":      true,
+		"registry.example.com:5000/ocp/release:4.8": true,
+		"other.example.com:5000/ocp/release:4.8":    true,
+		"other.example.com:5001/ocp/release:4.8":    false,
+		"secure.example.com/ocp/release:4.8":        false,
+		"quay.io/mirror/release:4.8":                true,
+	}
+	got := make(map[string]bool, len(alternates))
+	for _, alternate := range alternates {
+		got[alternate.Ref.Exact()] = alternate.Insecure
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	s = NewICSPOnErrorStrategy(client, "")
+	alternates, err = s.OnFailureWithSources(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, alternate := range alternates {
+		if alternate.Insecure {
+			t.Errorf("expected %s to be secure without insecure mirrors", alternate.Ref.Exact())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package strategy
+
+import (
+	"sort"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// WithKindPrecedence orders the loaded policies by kind, so that when a source appears in
+// policies of several kinds the mirrors of the kind listed first lead, for example
+// "ImageTagMirrorSet", "ImageDigestMirrorSet", "ImageContentSourcePolicy". Policies of
+// unlisted kinds follow those of listed kinds, and policies of the same kind keep the
+// order they were loaded in.
+func WithKindPrecedence(kinds ...string) Option {
+	return func(s *OnErrorStrategy) {
+		s.kindPrecedence = kinds
+	}
+}
+
+// WithDigestMirrorsOnly resolves mirrors the way a cluster pulls release payload images:
+// only images pulled by digest are mirrored, and only by the digest mirrors of
+// ImageContentSourcePolicies and ImageDigestMirrorSets. ImageTagMirrorSets are ignored
+// entirely, and an image pulled by tag is only retrieved from its source.
+func WithDigestMirrorsOnly() Option {
+	return func(s *OnErrorStrategy) {
+		s.digestOnly = true
+	}
+}
+
+// digestPolicies returns the policies of icspList that declare digest mirrors.
+func digestPolicies(icspList []operatorv1alpha1.ImageContentSourcePolicy) []operatorv1alpha1.ImageContentSourcePolicy {
+	var digest []operatorv1alpha1.ImageContentSourcePolicy
+	for i := range icspList {
+		if policyKind(&icspList[i]) != "ImageTagMirrorSet" {
+			digest = append(digest, icspList[i])
+		}
+	}
+	return digest
+}
+
+// policyKind returns the kind a policy was declared as. Policies listed from a cluster
+// carry no kind and are ImageContentSourcePolicies.
+func policyKind(icsp *operatorv1alpha1.ImageContentSourcePolicy) string {
+	if len(icsp.Kind) == 0 {
+		return "ImageContentSourcePolicy"
+	}
+	return icsp.Kind
+}
+
+// orderByKind stably sorts icspList by the precedence of the kind of each policy.
+func orderByKind(icspList []operatorv1alpha1.ImageContentSourcePolicy, kinds []string) []operatorv1alpha1.ImageContentSourcePolicy {
+	rank := make(map[string]int, len(kinds))
+	for i, kind := range kinds {
+		if _, ok := rank[kind]; !ok {
+			rank[kind] = i
+		}
+	}
+	rankOf := func(icsp *operatorv1alpha1.ImageContentSourcePolicy) int {
+		if i, ok := rank[policyKind(icsp)]; ok {
+			return i
+		}
+		return len(kinds)
+	}
+	ordered := append([]operatorv1alpha1.ImageContentSourcePolicy(nil), icspList...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return rankOf(&ordered[i]) < rankOf(&ordered[j])
+	})
+	return ordered
+}
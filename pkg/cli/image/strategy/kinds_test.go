@@ -0,0 +1,159 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const mixedKindPolicies = `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: icsp
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - icsp.example.com/ocp/release
+---
+apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: idms
+spec:
+  imageDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - idms.example.com/ocp/release
+---
+apiVersion: config.openshift.io/v1
+kind: ImageTagMirrorSet
+metadata:
+  name: itms
+spec:
+  imageTagMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - itms.example.com/ocp/release
+`
+
+func TestWithKindPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name: "load order",
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"icsp.example.com/ocp/release:4.8",
+				"idms.example.com/ocp/release:4.8",
+				"itms.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			name: "precedence",
+			opts: []Option{WithKindPrecedence("ImageTagMirrorSet", "ImageDigestMirrorSet", "ImageContentSourcePolicy")},
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"itms.example.com/ocp/release:4.8",
+				"idms.example.com/ocp/release:4.8",
+				"icsp.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			name: "unlisted kinds follow",
+			opts: []Option{WithKindPrecedence("ImageDigestMirrorSet")},
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"idms.example.com/ocp/release:4.8",
+				"icsp.example.com/ocp/release:4.8",
+				"itms.example.com/ocp/release:4.8",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(nil, "", append(tt.opts, WithInlinePolicy(mixedKindPolicies))...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithDigestMirrorsOnly(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		image    string
+		expected []string
+	}{
+		{
+			name:  "digest",
+			opts:  []Option{WithDigestMirrorsOnly()},
+			image: "quay.io/ocp/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"icsp.example.com/ocp/release@" + testDigest,
+				"idms.example.com/ocp/release@" + testDigest,
+			},
+		},
+		{
+			name:     "tag",
+			opts:     []Option{WithDigestMirrorsOnly()},
+			image:    "quay.io/ocp/release:4.8",
+			expected: []string{"quay.io/ocp/release:4.8"},
+		},
+		{
+			name:  "tag and digest",
+			opts:  []Option{WithDigestMirrorsOnly()},
+			image: "quay.io/ocp/release:4.8@" + testDigest,
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"icsp.example.com/ocp/release@" + testDigest,
+				"idms.example.com/ocp/release@" + testDigest,
+			},
+		},
+		{
+			name:  "every kind by default",
+			image: "quay.io/ocp/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"icsp.example.com/ocp/release@" + testDigest,
+				"idms.example.com/ocp/release@" + testDigest,
+				"itms.example.com/ocp/release@" + testDigest,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(nil, "", append(tt.opts, WithInlinePolicy(mixedKindPolicies))...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPolicyKind(t *testing.T) {
+	listed := newICSP("listed")
+	listed.Kind = ""
+	mirrorSet := newICSP("converted")
+	mirrorSet.Kind = "ImageDigestMirrorSet"
+	if kind := policyKind(&listed); kind != "ImageContentSourcePolicy" {
+		t.Errorf("expected a policy without a kind to be an ImageContentSourcePolicy, got %q", kind)
+	}
+	if kind := policyKind(&mirrorSet); kind != "ImageDigestMirrorSet" {
+		t.Errorf("expected the converted kind to be kept, got %q", kind)
+	}
+}
@@ -0,0 +1,64 @@
+package strategy
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// DefaultMaxPolicySize is the largest policy file, in bytes, that is loaded unless
+	// WithPolicyLimits sets another limit.
+	DefaultMaxPolicySize = 32 * 1024 * 1024
+	// DefaultMaxPolicyDocuments is the largest number of documents in a policy file that
+	// are loaded unless WithPolicyLimits sets another limit.
+	DefaultMaxPolicyDocuments = 10000
+)
+
+// WithPolicyLimits bounds the size in bytes and the number of documents of each policy
+// file, URL, inline policy, release manifest or artifact layer that is loaded, and the
+// size of registries.conf, overlay and image override files, so that a runaway or
+// malicious policy fails to load instead of exhausting memory. A limit of zero or less
+// removes that limit.
+func WithPolicyLimits(maxSize int64, maxDocuments int) Option {
+	return func(s *OnErrorStrategy) {
+		s.decode.maxSize = maxSize
+		s.decode.maxDocuments = maxDocuments
+	}
+}
+
+// readLimited reads all of r, failing once more than maxSize bytes have been read. A
+// maxSize of zero or less reads r without a limit.
+func readLimited(r io.Reader, maxSize int64) ([]byte, error) {
+	if maxSize <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("exceeds the maximum size of %d bytes", maxSize)
+	}
+	return data, nil
+}
+
+// readFileLimited reads the named file, failing if it is larger than maxSize bytes. A
+// maxSize of zero or less reads the file without a limit.
+func readFileLimited(path string, maxSize int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readLimited(f, maxSize)
+}
+
+// checkDocumentCount returns an error once count exceeds the document limit of opts.
+func checkDocumentCount(count int, opts decodeOptions) error {
+	if opts.maxDocuments > 0 && count > opts.maxDocuments {
+		return fmt.Errorf("exceeds the maximum of %d documents", opts.maxDocuments)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package strategy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithPolicyLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy-limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile("testdata/icsp.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies := filepath.Join(dir, "icsp.yaml")
+	if err := ioutil.WriteFile(policies, []byte(strings.Repeat(string(data)+"\n---\n", 3)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		expectErr string
+	}{
+		{name: "defaults"},
+		{name: "unlimited", opts: []Option{WithPolicyLimits(0, 0)}},
+		{name: "oversized", opts: []Option{WithPolicyLimits(int64(len(data)), 0)}, expectErr: "exceeds the maximum size of"},
+		{name: "too many documents", opts: []Option{WithPolicyLimits(0, 2)}, expectErr: "exceeds the maximum of 2 documents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(nil, policies, tt.opts...)
+			err := s.Init(context.Background())
+			if len(tt.expectErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectErr) || !strings.Contains(err.Error(), policies) {
+				t.Fatalf("expected an error containing %q naming the file, got %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestReadLimited(t *testing.T) {
+	if data, err := readLimited(strings.NewReader("abcd"), 4); err != nil || string(data) != "abcd" {
+		t.Errorf("expected content at the limit to be read, got %q, %v", data, err)
+	}
+	if _, err := readLimited(strings.NewReader("abcde"), 4); err == nil {
+		t.Errorf("expected an error for content over the limit")
+	}
+}
+
+func TestWithPolicyLimitsOtherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy-limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	registriesConf := write("registries.conf", "[[registry]]\nprefix = \"quay.io/ocp\"\nlocation = \"quay.io/ocp\"\n[[registry.mirror]]\nlocation = \"registry.example.com/ocp\"\n")
+	overlay := write("overlay.yaml", "add:\n- source: quay.io/ocp\n  mirrors:\n  - registry.example.com/ocp\n")
+	overrides := write("overrides.yaml", "overrides:\n- image: quay.io/ocp/release:4.8\n  mirrors: [registry.example.com/ocp/release:4.8]\n")
+	layer, err := ioutil.ReadFile("testdata/icsp.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifact := mustParse(t, "quay.io/ocp/policy:latest")
+	fetcher := &fakeArtifactFetcher{layers: map[string][][]byte{artifact.Exact(): {layer}}}
+	release := mustParse(t, "quay.io/ocp/release:4.8-payload")
+	provider := &fakeReleaseContentProvider{manifests: map[string]map[string][]byte{
+		release.Exact(): {"0000_00_icsp.yaml": layer},
+	}}
+
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "registries.conf", opt: WithRegistriesConf(registriesConf)},
+		{name: "overlay", opt: WithPolicyOverlayFile(overlay)},
+		{name: "image overrides", opt: WithImageOverrideFile(overrides)},
+		{name: "artifact", opt: WithPolicyArtifact(artifact, fetcher)},
+		{name: "release manifest", opt: WithReleasePolicy(release, provider)},
+		{name: "inline policy", opt: WithInlinePolicy(string(layer))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			image := mustParse(t, "quay.io/ocp/release:4.8")
+			if _, err := NewICSPOnErrorStrategy(nil, "", tt.opt).OnFailure(context.Background(), image); err != nil {
+				t.Fatalf("unexpected error within the default limits: %v", err)
+			}
+			_, err := NewICSPOnErrorStrategy(nil, "", tt.opt, WithPolicyLimits(16, 0)).OnFailure(context.Background(), image)
+			if err == nil || !strings.Contains(err.Error(), "exceeds the maximum size of 16 bytes") {
+				t.Errorf("expected the size limit to apply, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+)
+
+// LintFinding describes a policy entry that never affects the resolution of any image.
+type LintFinding struct {
+	// Policy and Source identify the entry that never applies.
+	Policy string
+	Source string
+	// ShadowedByPolicy and ShadowedBySource identify the entry that prevents it from applying.
+	ShadowedByPolicy string
+	ShadowedBySource string
+	Message          string
+}
+
+// Lint returns the entries of the loaded policies that are shadowed by another entry, so
+// that operators can remove them or fix the broader entry. Since a source applies to every
+// repository below it, a broad source acts as a wildcard for more specific ones. An entry
+// is shadowed when a redirect moves every image under its source elsewhere, or when an
+// earlier unconditional entry for the same or a broader source already provides each of
+// its mirrors.
+func (s *OnErrorStrategy) Lint(ctx context.Context) ([]LintFinding, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		policy     string
+		source     string
+		mirrors    []string
+		excluded   bool
+		conditions []string
+	}
+	var entries []entry
+	var redirected []redirect
+	for i := range icspList {
+		icsp := &icspList[i]
+		conditions, err := tagPatterns(icsp)
+		if err != nil {
+			return nil, err
+		}
+		declared, err := redirects(icsp)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sortedKeys(declared) {
+			redirected = append(redirected, redirect{policy: icsp.Name, source: source, target: declared[source]})
+		}
+		for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+			e := entry{policy: icsp.Name, source: normalizeRepository(rdm.Source), excluded: isExcluded(rdm)}
+			e.conditions = conditions[e.source]
+			for _, mirror := range rdm.Mirrors {
+				e.mirrors = append(e.mirrors, normalizeRepository(mirror))
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	var findings []LintFinding
+	for i, e := range entries {
+		if e.excluded {
+			continue
+		}
+		if r := shadowingRedirect(e.source, redirected); r != nil {
+			findings = append(findings, LintFinding{
+				Policy:           e.policy,
+				Source:           e.source,
+				ShadowedByPolicy: r.policy,
+				ShadowedBySource: r.source,
+				Message:          fmt.Sprintf("source %s in ImageContentSourcePolicy %s never applies, ImageContentSourcePolicy %s redirects %s to %s", e.source, e.policy, r.policy, r.source, r.target),
+			})
+			continue
+		}
+		for _, earlier := range entries[:i] {
+			if earlier.excluded || earlier.conditions != nil {
+				continue
+			}
+			suffix, ok := matchesSource(e.source, earlier.source)
+			if !ok || !providesAll(earlier.mirrors, suffix, e.mirrors) {
+				continue
+			}
+			findings = append(findings, LintFinding{
+				Policy:           e.policy,
+				Source:           e.source,
+				ShadowedByPolicy: earlier.policy,
+				ShadowedBySource: earlier.source,
+				Message:          fmt.Sprintf("source %s in ImageContentSourcePolicy %s never applies, its mirrors are already provided by source %s in ImageContentSourcePolicy %s", e.source, e.policy, earlier.source, earlier.policy),
+			})
+			break
+		}
+	}
+	return findings, nil
+}
+
+// shadowingRedirect returns the redirect that moves every image under source away from it,
+// or nil if there is none.
+func shadowingRedirect(source string, redirected []redirect) *redirect {
+	for i := range redirected {
+		r := &redirected[i]
+		if _, ok := matchesSource(source, r.source); !ok {
+			continue
+		}
+		if _, ok := matchesSource(r.target, source); ok {
+			continue
+		}
+		return r
+	}
+	return nil
+}
+
+// providesAll returns true if every mirror in mirrors is one of broader with suffix added.
+func providesAll(broader []string, suffix string, mirrors []string) bool {
+	provided := make(map[string]bool, len(broader))
+	for _, mirror := range broader {
+		provided[mirror+suffix] = true
+	}
+	for _, mirror := range mirrors {
+		if !provided[mirror] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestLint(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("broad", rdm("quay.io", "mirror.example.com", "backup.example.com")),
+		newICSP("specific",
+			rdm("quay.io/ocp/release", "mirror.example.com/ocp/release"),
+			rdm("quay.io/ocp/tools", "tools.example.com/ocp/tools"),
+		),
+		withAnnotations(
+			newICSP("conditional", rdm("registry.redhat.io", "mirror.example.com/redhat")),
+			map[string]string{TagPatternsAnnotation: `{"registry.redhat.io": ["v4.*"]}`},
+		),
+		newICSP("conditional-specific", rdm("registry.redhat.io/ubi8", "mirror.example.com/redhat/ubi8")),
+		withAnnotations(
+			newICSP("migration", rdm("docker.io/moved/app", "moved.example.com/app"), rdm("docker.io/moved/new", "new.example.com/app")),
+			map[string]string{RedirectsAnnotation: `{"docker.io/moved": "docker.io/moved/new"}`},
+		),
+	}}
+	findings, err := NewICSPOnErrorStrategy(client, "").Lint(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []LintFinding{
+		{
+			Policy:           "specific",
+			Source:           "quay.io/ocp/release",
+			ShadowedByPolicy: "broad",
+			ShadowedBySource: "quay.io",
+			Message:          "source quay.io/ocp/release in ImageContentSourcePolicy specific never applies, its mirrors are already provided by source quay.io in ImageContentSourcePolicy broad",
+		},
+		{
+			Policy:           "migration",
+			Source:           "docker.io/moved/app",
+			ShadowedByPolicy: "migration",
+			ShadowedBySource: "docker.io/moved",
+			Message:          "source docker.io/moved/app in ImageContentSourcePolicy migration never applies, ImageContentSourcePolicy migration redirects docker.io/moved to docker.io/moved/new",
+		},
+	}
+	if !reflect.DeepEqual(expected, findings) {
+		t.Errorf("expected %#v, got %#v", expected, findings)
+	}
+}
@@ -0,0 +1,86 @@
+package strategy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Metrics counts the work done by a strategy.
+type Metrics struct {
+	// ImagesResolved is the number of distinct images whose alternates were resolved.
+	ImagesResolved int
+	// MirrorsOffered is the number of mirrors returned across those images.
+	MirrorsOffered int
+	// PoliciesMatched is the number of policies that contributed mirrors across those images.
+	PoliciesMatched int
+	// Successes is the number of successes recorded for each mirror registry.
+	Successes map[string]int
+}
+
+func (m *Metrics) record(r *resolution) {
+	m.ImagesResolved++
+	m.MirrorsOffered += len(r.alternates) - 1
+	m.PoliciesMatched += len(r.matched)
+}
+
+// Metrics returns a snapshot of the metrics of the strategy.
+func (s *OnErrorStrategy) Metrics() Metrics {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	m := s.metrics
+	m.Successes = make(map[string]int, len(s.successes))
+	for registry, count := range s.successes {
+		m.Successes[registry] = count
+	}
+	return m
+}
+
+// WriteMetrics writes the metrics of the strategy to w in the Prometheus text exposition
+// format.
+func (s *OnErrorStrategy) WriteMetrics(w io.Writer) error {
+	m := s.Metrics()
+	buf := &bytes.Buffer{}
+	writeCounter(buf, "oc_image_mirror_images_resolved_total", "Number of distinct images whose alternates were resolved.", m.ImagesResolved)
+	writeCounter(buf, "oc_image_mirror_mirrors_offered_total", "Number of mirrors returned for resolved images.", m.MirrorsOffered)
+	writeCounter(buf, "oc_image_mirror_policies_matched_total", "Number of policies that contributed mirrors to resolved images.", m.PoliciesMatched)
+	if len(m.Successes) > 0 {
+		const name = "oc_image_mirror_successes_total"
+		fmt.Fprintf(buf, "# HELP %s Number of images retrieved from each mirror registry.\n# TYPE %s counter\n", name, name)
+		registries := make([]string, 0, len(m.Successes))
+		for registry := range m.Successes {
+			registries = append(registries, registry)
+		}
+		sort.Strings(registries)
+		for _, registry := range registries {
+			fmt.Fprintf(buf, "%s{registry=\"%s\"} %d\n", name, escapeLabelValue(registry), m.Successes[registry])
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteMetricsFile writes the metrics of the strategy to path, replacing its contents.
+func (s *OnErrorStrategy) WriteMetricsFile(path string) error {
+	buf := &bytes.Buffer{}
+	if err := s.WriteMetrics(buf); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("unable to write metrics to %s: %v", path, err)
+	}
+	return nil
+}
+
+func writeCounter(w io.Writer, name, help string, value int) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
@@ -0,0 +1,58 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com/ocp/release")),
+		newICSP("extra", rdm("quay.io/ocp", "backup.example.com/ocp")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	for _, image := range []string{"quay.io/ocp/release:4.8", "quay.io/ocp/release:4.8", "quay.io/ocp/tools:latest", "docker.io/library/busybox:latest"} {
+		if _, err := s.OnFailure(context.Background(), mustParse(t, image)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	s.RecordSuccess(mustParse(t, "quay.io/ocp/release:4.8"), mustParse(t, "registry.example.com/ocp/release:4.8"))
+
+	expected := `# HELP oc_image_mirror_images_resolved_total Number of distinct images whose alternates were resolved.
+# TYPE oc_image_mirror_images_resolved_total counter
+oc_image_mirror_images_resolved_total 3
+# HELP oc_image_mirror_mirrors_offered_total Number of mirrors returned for resolved images.
+# TYPE oc_image_mirror_mirrors_offered_total counter
+oc_image_mirror_mirrors_offered_total 4
+# HELP oc_image_mirror_policies_matched_total Number of policies that contributed mirrors to resolved images.
+# TYPE oc_image_mirror_policies_matched_total counter
+oc_image_mirror_policies_matched_total 3
+# HELP oc_image_mirror_successes_total Number of images retrieved from each mirror registry.
+# TYPE oc_image_mirror_successes_total counter
+oc_image_mirror_successes_total{registry="registry.example.com"} 1
+`
+	buf := &bytes.Buffer{}
+	if err := s.WriteMetrics(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != expected {
+		t.Errorf("unexpected metrics:\n%s", buf.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "metrics.prom")
+	if err := s.WriteMetricsFile(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != expected {
+		t.Errorf("unexpected metrics file:\n%s", data)
+	}
+}
@@ -0,0 +1,77 @@
+package strategy
+
+import (
+	"path"
+	"sort"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ImageMirror is an image and a repository it is mirrored to.
+type ImageMirror struct {
+	Source reference.DockerImageReference
+	Mirror reference.DockerImageReference
+}
+
+// MinimalRepositoryDigestMirrors returns a small set of policy entries under which every
+// source in mappings resolves to its mirror. Images that share a parent repository, and
+// are mirrored under the same name into a shared parent, are covered by a single entry
+// for the parents. Entries are sorted by source and keep mirrors in the order given.
+func MinimalRepositoryDigestMirrors(mappings []ImageMirror) []operatorv1alpha1.RepositoryDigestMirrors {
+	repositories := make(map[repositoryPair]map[string]bool)
+	for _, m := range mappings {
+		source, mirror := m.Source.AsRepository().Exact(), m.Mirror.AsRepository().Exact()
+		if p, ok := sharedParents(source, mirror); ok {
+			if repositories[p] == nil {
+				repositories[p] = make(map[string]bool)
+			}
+			repositories[p][source] = true
+		}
+	}
+
+	var sources []string
+	mirrors := make(map[string][]string)
+	add := func(source, mirror string) {
+		if _, ok := mirrors[source]; !ok {
+			sources = append(sources, source)
+		}
+		for _, existing := range mirrors[source] {
+			if existing == mirror {
+				return
+			}
+		}
+		mirrors[source] = append(mirrors[source], mirror)
+	}
+	for _, m := range mappings {
+		source, mirror := m.Source.AsRepository().Exact(), m.Mirror.AsRepository().Exact()
+		if p, ok := sharedParents(source, mirror); ok && len(repositories[p]) > 1 {
+			add(p.source, p.mirror)
+			continue
+		}
+		add(source, mirror)
+	}
+
+	sort.Strings(sources)
+	rdms := make([]operatorv1alpha1.RepositoryDigestMirrors, 0, len(sources))
+	for _, source := range sources {
+		rdms = append(rdms, operatorv1alpha1.RepositoryDigestMirrors{Source: source, Mirrors: mirrors[source]})
+	}
+	return rdms
+}
+
+// repositoryPair is a source repository and the repository it is mirrored to.
+type repositoryPair struct {
+	source, mirror string
+}
+
+// sharedParents returns the parent repositories of source and mirror if both have one and
+// they end in the same name, which allows the parents to stand in for them.
+func sharedParents(source, mirror string) (repositoryPair, bool) {
+	sourceParent, sourceName := path.Split(source)
+	mirrorParent, mirrorName := path.Split(mirror)
+	if sourceName != mirrorName || len(sourceParent) == 0 || len(mirrorParent) == 0 {
+		return repositoryPair{}, false
+	}
+	return repositoryPair{source: normalizeRepository(sourceParent), mirror: normalizeRepository(mirrorParent)}, true
+}
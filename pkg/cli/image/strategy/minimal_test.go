@@ -0,0 +1,67 @@
+package strategy
+
+import (
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestMinimalRepositoryDigestMirrors(t *testing.T) {
+	mapping := func(source, mirror string) ImageMirror {
+		return ImageMirror{Source: mustParse(t, source), Mirror: mustParse(t, mirror)}
+	}
+	tests := []struct {
+		name     string
+		mappings []ImageMirror
+		expected []operatorv1alpha1.RepositoryDigestMirrors
+	}{
+		{
+			name: "images under a shared namespace collapse",
+			mappings: []ImageMirror{
+				mapping("quay.io/ocp/etcd@"+testDigest, "mirror.example.com/ocp/etcd@"+testDigest),
+				mapping("quay.io/ocp/console:4.5", "mirror.example.com/ocp/console:4.5"),
+				mapping("quay.io/ocp/installer:4.5", "mirror.example.com/ocp/installer:4.5"),
+			},
+			expected: []operatorv1alpha1.RepositoryDigestMirrors{
+				rdm("quay.io/ocp", "mirror.example.com/ocp"),
+			},
+		},
+		{
+			name: "single and renamed images stay exact",
+			mappings: []ImageMirror{
+				mapping("quay.io/ocp/etcd:4.5", "mirror.example.com/ocp/etcd:4.5"),
+				mapping("quay.io/ocp/console:4.5", "mirror.example.com/renamed/ui:4.5"),
+				mapping("registry.redhat.io/ubi8/ubi:latest", "mirror.example.com/ubi8/ubi:latest"),
+				mapping("registry.redhat.io/ubi8/ubi:8.3", "backup.example.com/ubi8/ubi:8.3"),
+			},
+			expected: []operatorv1alpha1.RepositoryDigestMirrors{
+				rdm("quay.io/ocp/console", "mirror.example.com/renamed/ui"),
+				rdm("quay.io/ocp/etcd", "mirror.example.com/ocp/etcd"),
+				rdm("registry.redhat.io/ubi8/ubi", "mirror.example.com/ubi8/ubi", "backup.example.com/ubi8/ubi"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MinimalRepositoryDigestMirrors(tt.mappings)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			covering := []operatorv1alpha1.ImageContentSourcePolicy{newICSP("minimal", got...)}
+			for _, m := range tt.mappings {
+				alternates, err := alternativeImageSources(m.Source, covering)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				found := false
+				for _, alternate := range alternates {
+					found = found || alternate == m.Mirror
+				}
+				if !found {
+					t.Errorf("%s does not resolve to %s: %v", m.Source.Exact(), m.Mirror.Exact(), exactRefs(alternates))
+				}
+			}
+		})
+	}
+}
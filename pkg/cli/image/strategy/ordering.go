@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+)
+
+// WithHomeRegistry orders the mirrors hosted on host, or on a subdomain of it, ahead of
+// all other mirrors, treating them as the closest cache. The relative order of mirrors
+// is otherwise preserved and the requested image stays first.
+func WithHomeRegistry(host string) Option {
+	return func(s *OnErrorStrategy) {
+		s.homeRegistry = strings.ToLower(normalizeRepository(host))
+	}
+}
+
+// WithSortedMirrors orders the mirrors of each matching source alphabetically instead of in
+// the order the policy lists them, so that the output is deterministic and reviewable.
+// Sources keep their policy order and the requested image stays first.
+func WithSortedMirrors() Option {
+	return func(s *OnErrorStrategy) {
+		s.sortMirrors = true
+	}
+}
+
+// WithSameTLDPreference orders the mirrors hosted under the same top-level domain as the
+// requested image ahead of all other mirrors, as a cheap hint that they are closer. Hosts
+// addressed by IP or without a domain have no top-level domain and are not preferred.
+func WithSameTLDPreference() Option {
+	return func(s *OnErrorStrategy) {
+		s.preferSameTLD = true
+	}
+}
+
+// WithShuffledMirrors randomly orders the mirrors of each matching source, which are
+// equally preferred, to spread the load across them. The order is derived from seed and
+// the requested image, so that a run can be reproduced by passing the same seed. It
+// applies after WithSortedMirrors and before any other preference.
+func WithShuffledMirrors(seed int64) Option {
+	return func(s *OnErrorStrategy) {
+		s.shuffleMirrors = true
+		s.shuffleSeed = seed
+	}
+}
+
+// sortMirrors orders each run of mirrors declared by the same source alphabetically.
+func (r *resolution) sortMirrors() {
+	r.eachSourceRun(func(mirrors []Alternate) {
+		sort.SliceStable(mirrors, func(i, j int) bool {
+			return mirrors[i].Ref.Exact() < mirrors[j].Ref.Exact()
+		})
+	})
+}
+
+// shuffleMirrors randomly orders each run of mirrors declared by the same source. The
+// order only depends on seed and the requested image, not on the images resolved before.
+func (r *resolution) shuffleMirrors(seed int64) {
+	hash := fnv.New64a()
+	hash.Write([]byte(qualifiedRef(r.alternates[0].Ref).Exact()))
+	random := rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+	r.eachSourceRun(func(mirrors []Alternate) {
+		random.Shuffle(len(mirrors), func(i, j int) {
+			mirrors[i], mirrors[j] = mirrors[j], mirrors[i]
+		})
+	})
+}
+
+// eachSourceRun calls fn with each run of consecutive mirrors declared by the same source.
+func (r *resolution) eachSourceRun(fn func(mirrors []Alternate)) {
+	for start := 1; start < len(r.alternates); {
+		end := start + 1
+		for end < len(r.alternates) && r.alternates[end].Policy == r.alternates[start].Policy && r.alternates[end].Source == r.alternates[start].Source {
+			end++
+		}
+		fn(r.alternates[start:end])
+		start = end
+	}
+}
+
+// promoteMirrors moves the mirrors for which prefer returns true ahead of the remaining
+// mirrors, preserving relative order within both. The requested image is not moved.
+func (r *resolution) promoteMirrors(prefer func(Alternate) bool) {
+	if len(r.alternates) < 3 {
+		return
+	}
+	mirrors := r.alternates[1:]
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		return prefer(mirrors[i]) && !prefer(mirrors[j])
+	})
+}
+
+// registryHost returns the lowercased host of a registry, without any port or the brackets
+// of an IPv6 literal.
+func registryHost(registry string) string {
+	if host, _, err := net.SplitHostPort(registry); err == nil {
+		registry = host
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(registry, "["), "]"))
+}
+
+// topLevelDomain returns the last label of the host of registry, or an empty string for
+// hosts that are IP literals or have a single label.
+func topLevelDomain(registry string) string {
+	host := registryHost(registry)
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+	i := strings.LastIndex(host, ".")
+	if i < 0 {
+		return ""
+	}
+	return host[i+1:]
+}
+
+// isSameOrSubdomain returns true if host is domain or one of its subdomains.
+func isSameOrSubdomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,212 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithHomeRegistry(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release",
+			"mirror.dc2.example.com/ocp/release",
+			"internal.dc1.example.com:5000/ocp/release",
+			"registry.example.com/ocp/release",
+			"cache.internal.dc1.example.com/ocp/release",
+		)),
+	}}
+	image := mustParse(t, "quay.io/ocp/release@"+testDigest)
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name: "policy order without a home registry",
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"mirror.dc2.example.com/ocp/release@" + testDigest,
+				"internal.dc1.example.com:5000/ocp/release@" + testDigest,
+				"registry.example.com/ocp/release@" + testDigest,
+				"cache.internal.dc1.example.com/ocp/release@" + testDigest,
+			},
+		},
+		{
+			name: "home registry mirrors lead",
+			opts: []Option{WithHomeRegistry("internal.dc1.example.com")},
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"internal.dc1.example.com:5000/ocp/release@" + testDigest,
+				"cache.internal.dc1.example.com/ocp/release@" + testDigest,
+				"mirror.dc2.example.com/ocp/release@" + testDigest,
+				"registry.example.com/ocp/release@" + testDigest,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", tt.opts...)
+			alternates, err := s.OnFailure(context.Background(), image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithSortedMirrors(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "zeta.example.com/ocp/release", "alpha.example.com/ocp/release", "mid.example.com/ocp/release"),
+			rdm("quay.io/ocp", "beta.example.com/ocp", "aardvark.example.com/ocp"),
+		),
+	}}
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+
+	alternates, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alternates[1].Registry != "zeta.example.com" {
+		t.Errorf("expected policy order by default, got %v", exactRefs(alternates))
+	}
+
+	alternates, err = NewICSPOnErrorStrategy(client, "", WithSortedMirrors()).OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp/release:4.8",
+		"alpha.example.com/ocp/release:4.8",
+		"mid.example.com/ocp/release:4.8",
+		"zeta.example.com/ocp/release:4.8",
+		"aardvark.example.com/ocp/release:4.8",
+		"beta.example.com/ocp/release:4.8",
+	}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestWithSameTLDPreference(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("registry.example.de/ocp/release",
+			"mirror.example.com/ocp/release",
+			"10.0.0.1:5000/ocp/release",
+			"cache.example.de/ocp/release",
+		)),
+	}}
+	image := mustParse(t, "registry.example.de/ocp/release:4.8")
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name: "policy order without the preference",
+			expected: []string{
+				"registry.example.de/ocp/release:4.8",
+				"mirror.example.com/ocp/release:4.8",
+				"10.0.0.1:5000/ocp/release:4.8",
+				"cache.example.de/ocp/release:4.8",
+			},
+		},
+		{
+			name: "mirrors sharing the top-level domain lead",
+			opts: []Option{WithSameTLDPreference()},
+			expected: []string{
+				"registry.example.de/ocp/release:4.8",
+				"cache.example.de/ocp/release:4.8",
+				"mirror.example.com/ocp/release:4.8",
+				"10.0.0.1:5000/ocp/release:4.8",
+			},
+		},
+		{
+			name: "home registry still leads",
+			opts: []Option{WithSameTLDPreference(), WithHomeRegistry("mirror.example.com")},
+			expected: []string{
+				"registry.example.de/ocp/release:4.8",
+				"mirror.example.com/ocp/release:4.8",
+				"cache.example.de/ocp/release:4.8",
+				"10.0.0.1:5000/ocp/release:4.8",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", tt.opts...)
+			alternates, err := s.OnFailure(context.Background(), image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTopLevelDomain(t *testing.T) {
+	tests := map[string]string{
+		"quay.io":               "io",
+		"Registry.Example.COM":  "com",
+		"mirror.example.de:443": "de",
+		"localhost:5000":        "",
+		"10.0.0.1:5000":         "",
+		"[fd00::1]:5000":        "",
+	}
+	for registry, expected := range tests {
+		if actual := topLevelDomain(registry); actual != expected {
+			t.Errorf("%s: expected %q, got %q", registry, expected, actual)
+		}
+	}
+}
+
+func TestWithShuffledMirrors(t *testing.T) {
+	var mirrors []string
+	for i := 0; i < 8; i++ {
+		mirrors = append(mirrors, fmt.Sprintf("mirror%d.example.com/ocp/release", i))
+	}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", mirrors...)),
+		newICSP("fallback", rdm("quay.io/ocp", "fallback.example.com/ocp")),
+	}}
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+	resolve := func(seed int64, before ...string) []string {
+		s := NewICSPOnErrorStrategy(client, "", WithShuffledMirrors(seed))
+		for _, other := range before {
+			if _, err := s.OnFailure(context.Background(), mustParse(t, other)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		alternates, err := s.OnFailure(context.Background(), image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return exactRefs(alternates)
+	}
+
+	first := resolve(1)
+	if again := resolve(1, "quay.io/ocp/other:latest"); !reflect.DeepEqual(first, again) {
+		t.Errorf("expected the same order for the same seed, got %v and %v", first, again)
+	}
+	if other := resolve(2); reflect.DeepEqual(first, other) {
+		t.Errorf("expected a different order for another seed, got %v", other)
+	}
+	if first[0] != "quay.io/ocp/release:4.8" || first[len(first)-1] != "fallback.example.com/ocp/release:4.8" {
+		t.Errorf("expected the requested image first and the mirrors of the broader source last, got %v", first)
+	}
+	var unshuffled []string
+	for _, mirror := range mirrors {
+		unshuffled = append(unshuffled, mirror+":4.8")
+	}
+	if shuffled := first[1 : len(first)-1]; reflect.DeepEqual(shuffled, unshuffled) {
+		t.Errorf("expected the mirrors to be shuffled, got %v", shuffled)
+	}
+}
@@ -0,0 +1,141 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// PolicyOverlay adjusts the source to mirror mappings of the loaded policies, so that
+// variations such as dev, stage and prod can share one base policy. Removals are applied
+// before additions.
+type PolicyOverlay struct {
+	// Name identifies the overlay in the policy holding its additions.
+	Name string `json:"name,omitempty"`
+	// Add appends mirrors for a source after those declared by the base policies.
+	Add []operatorv1alpha1.RepositoryDigestMirrors `json:"add,omitempty"`
+	// Remove drops the listed mirrors of a source from every base policy, or the source
+	// entirely when no mirrors are listed.
+	Remove []operatorv1alpha1.RepositoryDigestMirrors `json:"remove,omitempty"`
+}
+
+// WithPolicyOverlay applies overlay to the loaded policies. Overlays are applied in the
+// order they are provided.
+func WithPolicyOverlay(overlay PolicyOverlay) Option {
+	return func(s *OnErrorStrategy) {
+		s.overlays = append(s.overlays, func() (*PolicyOverlay, error) {
+			return &overlay, nil
+		})
+	}
+}
+
+// WithPolicyOverlayFile applies the PolicyOverlay read from the YAML or JSON file at path.
+func WithPolicyOverlayFile(path string) Option {
+	return func(s *OnErrorStrategy) {
+		s.overlays = append(s.overlays, func() (*PolicyOverlay, error) {
+			return readPolicyOverlay(path, s.decode)
+		})
+	}
+}
+
+// WithNamespaceOverlay applies overlay on top of the other overlays when resolving images
+// for objects in namespace with ResolveInNamespace or ResolveObjectImages, so that tenants
+// can be given their own mirrors. Overlays for the same namespace are applied in the order
+// they are provided, and other namespaces are unaffected.
+func WithNamespaceOverlay(namespace string, overlay PolicyOverlay) Option {
+	return func(s *OnErrorStrategy) {
+		if s.namespaceOverlays == nil {
+			s.namespaceOverlays = make(map[string][]PolicyOverlay)
+		}
+		if len(overlay.Name) == 0 {
+			overlay.Name = "namespace-" + namespace
+		}
+		s.namespaceOverlays[namespace] = append(s.namespaceOverlays[namespace], overlay)
+	}
+}
+
+// ResolveInNamespace returns the alternates of locator for an object in namespace, with
+// the overlays of that namespace applied.
+func (s *OnErrorStrategy) ResolveInNamespace(ctx context.Context, namespace string, locator reference.DockerImageReference) ([]reference.DockerImageReference, error) {
+	return s.onFailure(ctx, namespace, locator)
+}
+
+func readPolicyOverlay(path string, opts decodeOptions) (*PolicyOverlay, error) {
+	data, err := readFileLimited(path, opts.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read policy overlay %s: %v", path, err)
+	}
+	unmarshal := yaml.Unmarshal
+	if opts.strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+	overlay := &PolicyOverlay{}
+	if err := unmarshal(data, overlay); err != nil {
+		return nil, fmt.Errorf("unable to parse policy overlay %s: %v", path, err)
+	}
+	if len(overlay.Name) == 0 {
+		overlay.Name = path
+	}
+	return overlay, nil
+}
+
+// Apply returns icspList with the overlay applied. The objects in icspList are not
+// modified, and additions are held by a new policy named after the overlay.
+func (o *PolicyOverlay) Apply(icspList []operatorv1alpha1.ImageContentSourcePolicy) []operatorv1alpha1.ImageContentSourcePolicy {
+	removeAll := make(map[string]bool)
+	remove := make(map[string]map[string]bool)
+	for _, rdm := range o.Remove {
+		source := normalizeRepository(rdm.Source)
+		if len(rdm.Mirrors) == 0 {
+			removeAll[source] = true
+			continue
+		}
+		if remove[source] == nil {
+			remove[source] = make(map[string]bool)
+		}
+		for _, mirror := range rdm.Mirrors {
+			remove[source][normalizeRepository(mirror)] = true
+		}
+	}
+
+	result := make([]operatorv1alpha1.ImageContentSourcePolicy, 0, len(icspList)+1)
+	for i := range icspList {
+		icsp := icspList[i].DeepCopy()
+		var rdms []operatorv1alpha1.RepositoryDigestMirrors
+		for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+			source := normalizeRepository(rdm.Source)
+			if removeAll[source] {
+				continue
+			}
+			if removed := remove[source]; removed != nil {
+				var mirrors []string
+				for _, mirror := range rdm.Mirrors {
+					if !removed[normalizeRepository(mirror)] {
+						mirrors = append(mirrors, mirror)
+					}
+				}
+				rdm.Mirrors = mirrors
+			}
+			rdms = append(rdms, rdm)
+		}
+		icsp.Spec.RepositoryDigestMirrors = rdms
+		result = append(result, *icsp)
+	}
+
+	if len(o.Add) > 0 {
+		added := operatorv1alpha1.ImageContentSourcePolicy{
+			Spec: operatorv1alpha1.ImageContentSourcePolicySpec{
+				RepositoryDigestMirrors: append([]operatorv1alpha1.RepositoryDigestMirrors(nil), o.Add...),
+			},
+		}
+		added.Name = o.Name
+		added.APIVersion = operatorv1alpha1.GroupVersion.String()
+		added.Kind = "ImageContentSourcePolicy"
+		result = append(result, added)
+	}
+	return result
+}
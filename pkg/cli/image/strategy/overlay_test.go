@@ -0,0 +1,125 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestPolicyOverlayFile(t *testing.T) {
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithPolicyOverlayFile("testdata/overlay-stage.yaml"))
+	explanation, err := s.Explain(context.Background(), mustParse(t, "quay.io/ocp-test/release@"+testDigest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Alternate{
+		{Ref: mustParse(t, "quay.io/ocp-test/release@"+testDigest)},
+		{Ref: mustParse(t, "mirror.example.com/ocp-test/release@"+testDigest), Policy: "release", Source: "quay.io/ocp-test/release"},
+		{Ref: mustParse(t, "registry.example.com/ocp-test/release@"+testDigest), Policy: "release", Source: "quay.io/ocp-test"},
+		{Ref: mustParse(t, "stage.example.com/ocp-test/release@"+testDigest), Policy: "stage", Source: "quay.io/ocp-test/release"},
+	}
+	if !reflect.DeepEqual(explanation.Alternates, expected) {
+		t.Errorf("expected %v, got %v", expected, explanation.Alternates)
+	}
+}
+
+func TestPolicyOverlaysAppliedInOrder(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("base",
+			rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com/ocp/release"),
+			rdm("quay.io/ocp/other", "registry.example.com/ocp/other"),
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "",
+		WithPolicyOverlay(PolicyOverlay{
+			Name: "first",
+			Add:  []operatorv1alpha1.RepositoryDigestMirrors{rdm("quay.io/ocp/release", "dev.example.com/ocp/release")},
+		}),
+		WithPolicyOverlay(PolicyOverlay{
+			Name: "second",
+			Remove: []operatorv1alpha1.RepositoryDigestMirrors{
+				rdm("quay.io/ocp/release", "mirror.example.com/ocp/release/", "dev.example.com/ocp/release"),
+				rdm("quay.io/ocp/other"),
+			},
+		}),
+	)
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image:    "quay.io/ocp/release:4.5",
+			expected: []string{"quay.io/ocp/release:4.5", "registry.example.com/ocp/release:4.5"},
+		},
+		{
+			image:    "quay.io/ocp/other:4.5",
+			expected: []string{"quay.io/ocp/other:4.5"},
+		},
+	}
+	for _, tt := range tests {
+		alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.image, tt.expected, got)
+		}
+	}
+	if len(client.items[0].Spec.RepositoryDigestMirrors[0].Mirrors) != 2 {
+		t.Errorf("expected the base policy not to be modified")
+	}
+}
+
+func TestWithNamespaceOverlay(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "",
+		WithNamespaceOverlay("team-a", PolicyOverlay{Add: []operatorv1alpha1.RepositoryDigestMirrors{rdm("quay.io/ocp/release", "team-a.example.com/ocp/release")}}),
+		WithNamespaceOverlay("team-b", PolicyOverlay{Remove: []operatorv1alpha1.RepositoryDigestMirrors{rdm("quay.io/ocp/release")}}),
+	)
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+	tests := []struct {
+		namespace string
+		expected  []string
+	}{
+		{namespace: "team-a", expected: []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8", "team-a.example.com/ocp/release:4.8"}},
+		{namespace: "team-b", expected: []string{"quay.io/ocp/release:4.8"}},
+		{namespace: "other", expected: []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			alternates, err := s.ResolveInNamespace(context.Background(), tt.namespace, image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+	alternates, err := s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, tests[2].expected) {
+		t.Errorf("expected no namespace overlay to apply, got %v", got)
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "app"},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Image: image.Exact()}}},
+	}
+	images, err := s.ResolveObjectImages(context.Background(), pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := exactRefs(images["spec.containers[0].image"]); !reflect.DeepEqual(got, tests[1].expected) {
+		t.Errorf("expected the overlay of the pod namespace to apply, got %v", got)
+	}
+}
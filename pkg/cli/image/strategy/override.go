@@ -0,0 +1,132 @@
+package strategy
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ImageOverrides lists explicit mirrors for individual images, for one-off exceptions to
+// the policies.
+type ImageOverrides struct {
+	Overrides []ImageOverride `json:"overrides"`
+}
+
+// ImageOverride replaces the mirrors of one image.
+type ImageOverride struct {
+	// Image is the full reference of the image, including its tag or digest. An image
+	// requested by both tag and digest uses the override of its digest, or else that of its
+	// tag, whose mirrors are then pinned to the requested digest.
+	Image string `json:"image"`
+	// Mirrors are the full references to try after Image, in order.
+	Mirrors []string `json:"mirrors"`
+}
+
+// WithImageOverrideFile reads ImageOverrides from the YAML or JSON file at path. The
+// mirrors of an image listed in the file are used instead of those of the policies, while
+// the configured filters and checks still apply. Images not listed are resolved from the
+// policies as usual. When several files list an image, the first one wins.
+func WithImageOverrideFile(path string) Option {
+	return func(s *OnErrorStrategy) {
+		s.overrideFiles = append(s.overrideFiles, path)
+	}
+}
+
+// loadOverrides reads the override files the first time it is called. The caller must
+// hold s.lock.
+func (s *OnErrorStrategy) loadOverrides() error {
+	if s.overrides != nil || len(s.overrideFiles) == 0 {
+		return nil
+	}
+	overrides := make(map[reference.DockerImageReference][]Alternate)
+	for _, path := range s.overrideFiles {
+		loaded, err := s.readImageOverrides(path)
+		if err != nil {
+			return err
+		}
+		for image, alternates := range loaded {
+			if _, ok := overrides[image]; !ok {
+				overrides[image] = alternates
+			}
+		}
+	}
+	s.overrides = overrides
+	return nil
+}
+
+// resolveOverride returns the resolution of locator from the override files, or nil if
+// they do not list it. An unqualified locator such as busybox:latest matches the override
+// of the image it refers to, docker.io/library/busybox:latest. The caller must hold
+// s.lock.
+func (s *OnErrorStrategy) resolveOverride(locator reference.DockerImageReference) (*resolution, error) {
+	if err := s.loadOverrides(); err != nil {
+		return nil, err
+	}
+	key := qualifiedRef(locator)
+	mirrors, ok := s.overrides[key]
+	if !ok && len(key.Tag) > 0 && len(key.ID) > 0 {
+		byDigest, byTag := key, key
+		byDigest.Tag, byTag.ID = "", ""
+		if mirrors, ok = s.overrides[byDigest]; !ok {
+			if mirrors, ok = s.overrides[byTag]; ok {
+				mirrors = pinAlternates(mirrors, locator.ID)
+			}
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+	klog.V(4).Infof("Using the overridden mirrors of %s", locator.Exact())
+	return &resolution{alternates: append([]Alternate{{Ref: locator}}, mirrors...), sourceMatched: true}, nil
+}
+
+// pinAlternates returns a copy of alternates with those that have no digest pinned to
+// dgst, so that the digest stays authoritative and their tags are only informational.
+func pinAlternates(alternates []Alternate, dgst string) []Alternate {
+	pinned := make([]Alternate, 0, len(alternates))
+	for _, alternate := range alternates {
+		if len(alternate.Ref.ID) == 0 {
+			alternate.Ref.ID = dgst
+		}
+		pinned = append(pinned, alternate)
+	}
+	return pinned
+}
+
+func (s *OnErrorStrategy) readImageOverrides(path string) (map[reference.DockerImageReference][]Alternate, error) {
+	data, err := readFileLimited(path, s.decode.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read image overrides %s: %v", path, err)
+	}
+	unmarshal := yaml.Unmarshal
+	if s.decode.strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+	var file ImageOverrides
+	if err := unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse image overrides %s: %v", path, err)
+	}
+	overrides := make(map[reference.DockerImageReference][]Alternate, len(file.Overrides))
+	for _, override := range file.Overrides {
+		image, err := s.parse(override.Image)
+		if err != nil {
+			return nil, fmt.Errorf("invalid image %q in image overrides %s: %v", override.Image, path, err)
+		}
+		if len(image.Tag) == 0 && len(image.ID) == 0 {
+			return nil, fmt.Errorf("image %q in image overrides %s must have a tag or digest", override.Image, path)
+		}
+		var alternates []Alternate
+		for _, mirror := range override.Mirrors {
+			mirrorRef, err := s.parse(mirror)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mirror %q for image %q in image overrides %s: %v", mirror, override.Image, path, err)
+			}
+			alternates = append(alternates, Alternate{Ref: mirrorRef, Policy: path, Source: image.Exact()})
+		}
+		overrides[qualifiedRef(image)] = alternates
+	}
+	return overrides, nil
+}
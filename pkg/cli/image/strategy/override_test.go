@@ -0,0 +1,121 @@
+package strategy
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithImageOverrideFile(t *testing.T) {
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithImageOverrideFile("testdata/overrides.yaml"))
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "quay.io/ocp-test/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/release@" + testDigest,
+				"exceptions.example.com/ocp-test/release@" + testDigest,
+				"registry.example.com/one-off/release@" + testDigest,
+			},
+		},
+		{
+			image: "quay.io/ocp-test/release:4.8",
+			expected: []string{
+				"quay.io/ocp-test/release:4.8",
+				"registry.example.com/ocp-test/release:4.8",
+				"mirror.example.com/ocp-test/release:4.8",
+			},
+		},
+		{
+			image:    "busybox:1.36",
+			expected: []string{"busybox:1.36", "registry.example.com/library/busybox:1.36"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+
+	filtered := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithImageOverrideFile("testdata/overrides.yaml"), WithBlockedRegistries("exceptions.example.com"))
+	alternates, err := filtered.OnFailure(context.Background(), mustParse(t, tests[0].image))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alternates) != 2 || alternates[1].Registry != "registry.example.com" {
+		t.Errorf("expected the blocked override to be dropped, got %v", exactRefs(alternates))
+	}
+}
+
+func TestWithImageOverrideFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		"missing tag":    "overrides:\n- image: quay.io/ocp/release\n  mirrors: [registry.example.com/ocp/release:4.8]\n",
+		"invalid mirror": "overrides:\n- image: quay.io/ocp/release:4.8\n  mirrors: [Invalid//Mirror]\n",
+		"invalid yaml":   "overrides: {\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name+".yaml")
+			if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithImageOverrideFile(path))
+			if err := s.Init(context.Background()); err == nil {
+				t.Errorf("expected Init to report the invalid override file")
+			}
+		})
+	}
+}
+
+func TestImageOverrideTagAndDigest(t *testing.T) {
+	const otherDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000001"
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	content := "overrides:\n- image: quay.io/ocp-test/other:4.8\n  mirrors: [hotfix.example.com/ocp-test/other:4.8, pinned.example.com/ocp-test/other@" + otherDigest + "]\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithImageOverrideFile("testdata/overrides.yaml"), WithImageOverrideFile(path))
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "quay.io/ocp-test/release:4.8@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/release@" + testDigest,
+				"exceptions.example.com/ocp-test/release@" + testDigest,
+				"registry.example.com/one-off/release@" + testDigest,
+			},
+		},
+		{
+			image: "quay.io/ocp-test/other:4.8@" + testDigest,
+			expected: []string{
+				"quay.io/ocp-test/other@" + testDigest,
+				"hotfix.example.com/ocp-test/other@" + testDigest,
+				"pinned.example.com/ocp-test/other@" + otherDigest,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
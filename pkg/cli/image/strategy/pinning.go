@@ -0,0 +1,184 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/opencontainers/go-digest"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// DigestResolver returns the digest a tag currently points to, usually with a HEAD request
+// against the registry.
+type DigestResolver interface {
+	ResolveDigest(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error)
+}
+
+// DigestResolverFunc adapts a function to the DigestResolver interface.
+type DigestResolverFunc func(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error)
+
+// ResolveDigest calls f.
+func (f DigestResolverFunc) ResolveDigest(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error) {
+	return f(ctx, ref)
+}
+
+// WithDigestPinning looks up the digest of images requested by tag with resolver before
+// their alternates are resolved, so that every alternate, including the requested image,
+// is pinned to that digest and mirrors that only hold content by digest can serve it. The
+// tag is still used to match tag conditions. An image whose digest cannot be looked up is
+// resolved by tag. This costs a request per image and is off by default.
+func WithDigestPinning(resolver DigestResolver) Option {
+	return func(s *OnErrorStrategy) {
+		s.digestResolver = resolver
+	}
+}
+
+// WithRequiredDigest fails the resolution of images that are not pinned to a digest, so
+// that every pull is reproducible. With WithDigestPinning, an image requested by tag is
+// accepted if its digest can be looked up.
+func WithRequiredDigest() Option {
+	return func(s *OnErrorStrategy) {
+		s.requireDigest = true
+	}
+}
+
+// WithCanonicalDigests pins every alternate requested by tag to the digest its own tag
+// points to, as looked up with resolver once the alternates are resolved, so that callers
+// receive only digest references. Unlike WithDigestPinning, each mirror is looked up on its
+// own, so mirrors are not assumed to hold the digest of the requested image. Alternates
+// whose digest cannot be looked up are returned by tag.
+func WithCanonicalDigests(resolver DigestResolver) Option {
+	return func(s *OnErrorStrategy) {
+		s.canonicalDigests = resolver
+	}
+}
+
+// canonicalizeDigests pins the alternates of r that have no digest to the digest their
+// tag points to, if canonical digests were requested.
+func (s *OnErrorStrategy) canonicalizeDigests(ctx context.Context, r *resolution) {
+	if s.canonicalDigests == nil {
+		return
+	}
+	for i := range r.alternates {
+		ref := &r.alternates[i].Ref
+		if len(ref.ID) > 0 || len(ref.Tag) == 0 {
+			continue
+		}
+		dgst, err := s.canonicalDigests.ResolveDigest(ctx, *ref)
+		if err == nil {
+			err = dgst.Validate()
+		}
+		if err != nil {
+			klog.V(2).Infof("Unable to look up the digest of %s, returning it by tag: %v", ref.Exact(), err)
+			continue
+		}
+		ref.ID = dgst.String()
+	}
+}
+
+// WithTagDigestCache remembers the digest of each tag that was resolved pinned to one, as
+// with WithDigestPinning or when an image is requested by both tag and digest, so that
+// later requests for the tag alone are pinned to that digest and matched against digest
+// mirrors without looking it up again. The digests are forgotten whenever the loaded
+// policies change, and when Init is called.
+func WithTagDigestCache() Option {
+	return func(s *OnErrorStrategy) {
+		s.tagDigests = &tagDigestCache{}
+	}
+}
+
+// tagDigestCache maps tags to the digest they were last resolved at. It is safe for
+// concurrent use, since digests are pinned without holding the strategy lock.
+type tagDigestCache struct {
+	lock sync.Mutex
+	// fingerprint is the fingerprint of the policies the digests were resolved against.
+	fingerprint string
+	digests     map[reference.DockerImageReference]string
+}
+
+// get returns the digest cached for the tag of ref.
+func (c *tagDigestCache) get(ref reference.DockerImageReference) (string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	dgst, ok := c.digests[ref]
+	return dgst, ok
+}
+
+// add caches the digest of a reference with both a tag and a digest.
+func (c *tagDigestCache) add(ref reference.DockerImageReference) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.digests == nil {
+		c.digests = make(map[reference.DockerImageReference]string)
+	}
+	dgst := ref.ID
+	ref.ID = ""
+	c.digests[ref] = dgst
+}
+
+// observe forgets every digest if fingerprint differs from that of the policies they were
+// resolved against.
+func (c *tagDigestCache) observe(fingerprint string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if fingerprint != c.fingerprint {
+		c.fingerprint = fingerprint
+		c.digests = nil
+	}
+}
+
+// observePolicies loads the policies so that the cached tag digests are forgotten if the
+// policies changed since they were resolved, before any of them is used. A failure to
+// load is left for the resolution to report.
+func (s *OnErrorStrategy) observePolicies(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return
+	}
+	if fingerprint, err := s.fingerprintFor(icspList); err == nil {
+		s.tagDigests.observe(fingerprint)
+	}
+}
+
+// checkDigest returns an error if a digest is required and locator has none.
+func (s *OnErrorStrategy) checkDigest(locator reference.DockerImageReference) error {
+	if s.requireDigest && len(locator.ID) == 0 {
+		return fmt.Errorf("image %q is not pinned to a digest, which is required", locator.Exact())
+	}
+	return nil
+}
+
+// pinDigest returns locator pinned to the digest its tag points to, as cached or looked
+// up, or locator itself if it is already pinned, pinning is off or the digest cannot be
+// looked up.
+func (s *OnErrorStrategy) pinDigest(ctx context.Context, locator reference.DockerImageReference) reference.DockerImageReference {
+	if len(locator.ID) > 0 || len(locator.Tag) == 0 {
+		return locator
+	}
+	if s.tagDigests != nil {
+		s.observePolicies(ctx)
+		if dgst, ok := s.tagDigests.get(locator); ok {
+			klog.V(4).Infof("Pinning %s to cached digest %s", locator.Exact(), dgst)
+			locator.ID = dgst
+			return locator
+		}
+	}
+	if s.digestResolver == nil {
+		return locator
+	}
+	dgst, err := s.digestResolver.ResolveDigest(ctx, locator)
+	if err == nil {
+		err = dgst.Validate()
+	}
+	if err != nil {
+		klog.V(2).Infof("Unable to look up the digest of %s, resolving its mirrors by tag: %v", locator.Exact(), err)
+		return locator
+	}
+	locator.ID = dgst.String()
+	return locator
+}
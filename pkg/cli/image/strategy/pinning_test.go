@@ -0,0 +1,247 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestWithDigestPinning(t *testing.T) {
+	tagged := newICSP("tagged", rdm("quay.io/ocp/tagged", "registry.example.com/ocp/tagged"))
+	tagged.Annotations = map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/tagged": ["4.*"]}`}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+		tagged,
+	}}
+	var lookups []string
+	resolver := DigestResolverFunc(func(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error) {
+		lookups = append(lookups, ref.Exact())
+		if ref.Tag == "missing" {
+			return "", fmt.Errorf("manifest unknown")
+		}
+		return digest.Digest(testDigest), nil
+	})
+	tests := []struct {
+		image    string
+		expected []string
+		lookedUp bool
+	}{
+		{
+			image:    "quay.io/ocp/release:4.8",
+			expected: []string{"quay.io/ocp/release@" + testDigest, "registry.example.com/ocp/release@" + testDigest},
+			lookedUp: true,
+		},
+		{
+			image:    "quay.io/ocp/tagged:4.8",
+			expected: []string{"quay.io/ocp/tagged@" + testDigest, "registry.example.com/ocp/tagged@" + testDigest},
+			lookedUp: true,
+		},
+		{
+			image:    "quay.io/ocp/release:missing",
+			expected: []string{"quay.io/ocp/release:missing", "registry.example.com/ocp/release:missing"},
+			lookedUp: true,
+		},
+		{
+			image:    "quay.io/ocp/release@" + testDigest,
+			expected: []string{"quay.io/ocp/release@" + testDigest, "registry.example.com/ocp/release@" + testDigest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			lookups = nil
+			s := NewICSPOnErrorStrategy(client, "", WithDigestPinning(resolver))
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			if lookedUp := len(lookups) > 0; lookedUp != tt.lookedUp {
+				t.Errorf("expected a digest lookup %t, got %v", tt.lookedUp, lookups)
+			}
+		})
+	}
+
+	s := NewICSPOnErrorStrategy(client, "", WithDigestPinning(resolver))
+	best, err := s.BestMirror(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if best.Exact() != "registry.example.com/ocp/release@"+testDigest {
+		t.Errorf("unexpected best mirror %s", best.Exact())
+	}
+}
+
+func TestWithRequiredDigest(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	resolver := DigestResolverFunc(func(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error) {
+		if ref.Tag == "missing" {
+			return "", fmt.Errorf("manifest unknown")
+		}
+		return digest.Digest(testDigest), nil
+	})
+	tests := []struct {
+		name        string
+		image       string
+		opts        []Option
+		expectedErr string
+	}{
+		{name: "digest", image: "quay.io/ocp/release@" + testDigest},
+		{name: "tag", image: "quay.io/ocp/release:4.8", expectedErr: `image "quay.io/ocp/release:4.8" is not pinned to a digest`},
+		{name: "pinned tag", image: "quay.io/ocp/release:4.8", opts: []Option{WithDigestPinning(resolver)}},
+		{name: "unpinned tag", image: "quay.io/ocp/release:missing", opts: []Option{WithDigestPinning(resolver)}, expectedErr: "is not pinned to a digest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", append(tt.opts, WithRequiredDigest())...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if len(tt.expectedErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(alternates) != 2 {
+				t.Errorf("unexpected alternates %v", exactRefs(alternates))
+			}
+		})
+	}
+
+	alternates, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil || len(alternates) != 2 {
+		t.Errorf("expected tags to be accepted by default, got %v, %v", exactRefs(alternates), err)
+	}
+}
+
+func TestWithTagDigestCache(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	lookups := 0
+	current := digest.Digest(testDigest)
+	resolver := DigestResolverFunc(func(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error) {
+		lookups++
+		return current, nil
+	})
+	s := NewICSPOnErrorStrategy(client, "", WithDigestPinning(resolver), WithTagDigestCache(), WithResolutionCache(10))
+	resolve := func(image string) []string {
+		t.Helper()
+		alternates, err := s.OnFailure(context.Background(), mustParse(t, image))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return exactRefs(alternates)
+	}
+
+	expected := []string{"quay.io/ocp/release@" + testDigest, "registry.example.com/ocp/release@" + testDigest}
+	for i := 0; i < 3; i++ {
+		if actual := resolve("quay.io/ocp/release:4.8"); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %v, got %v", expected, actual)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected the digest of a repeated tag to be looked up once, got %d lookups", lookups)
+	}
+
+	client.items = []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "other.example.com/ocp/release")),
+	}
+	lookups = 0
+	current = digest.FromString("retagged")
+	expected = []string{"quay.io/ocp/release@" + current.String(), "other.example.com/ocp/release@" + current.String()}
+	for i := 0; i < 2; i++ {
+		if actual := resolve("quay.io/ocp/release:4.8"); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %v, got %v", expected, actual)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected the digest to be looked up again right after the policies changed, got %d lookups", lookups)
+	}
+
+	t.Run("discovered digest", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(client, "", WithTagDigestCache())
+		if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8@"+testDigest)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"quay.io/ocp/release@" + testDigest, "other.example.com/ocp/release@" + testDigest}
+		if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %v, got %v", expected, actual)
+		}
+	})
+}
+
+func TestWithCanonicalDigests(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release", "second.example.com/ocp/release", "missing.example.com/ocp/release")),
+	}}
+	mirrorDigest := digest.FromString("mirror")
+	var lookups []string
+	resolver := DigestResolverFunc(func(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error) {
+		lookups = append(lookups, ref.Exact())
+		switch ref.Registry {
+		case "quay.io", "first.example.com":
+			return digest.Digest(testDigest), nil
+		case "second.example.com":
+			return mirrorDigest, nil
+		}
+		return "", fmt.Errorf("manifest unknown")
+	})
+	tests := []struct {
+		image    string
+		expected []string
+		lookups  int
+	}{
+		{
+			image: "quay.io/ocp/release:4.8",
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"first.example.com/ocp/release@" + testDigest,
+				"second.example.com/ocp/release@" + mirrorDigest.String(),
+				"missing.example.com/ocp/release:4.8",
+			},
+			lookups: 4,
+		},
+		{
+			image: "quay.io/ocp/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"first.example.com/ocp/release@" + testDigest,
+				"second.example.com/ocp/release@" + testDigest,
+				"missing.example.com/ocp/release@" + testDigest,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			lookups = nil
+			s := NewICSPOnErrorStrategy(client, "", WithCanonicalDigests(resolver))
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+			if len(lookups) != tt.lookups {
+				t.Errorf("expected %d digest lookups, got %v", tt.lookups, lookups)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// DefaultAttemptTimeout is the time allowed for each attempt of a plan when no timeout
+// has been configured.
+const DefaultAttemptTimeout = 30 * time.Second
+
+// Attempt is one location of an attempt plan and the time allowed for trying it.
+type Attempt struct {
+	Ref     reference.DockerImageReference
+	Timeout time.Duration
+	// Permanent is set when a previous attempt against Ref failed in a way that will recur,
+	// and the location should be skipped.
+	Permanent bool
+}
+
+// WithAttemptTimeout sets the time allowed for each attempt returned by AttemptPlan.
+// Values that are not positive restore DefaultAttemptTimeout.
+func WithAttemptTimeout(timeout time.Duration) Option {
+	return func(s *OnErrorStrategy) {
+		if timeout <= 0 {
+			timeout = DefaultAttemptTimeout
+		}
+		s.attemptTimeout = timeout
+	}
+}
+
+// AttemptPlan returns the alternates of locator in the order OnFailure would return them,
+// each paired with the time a caller should allow for it so that every runner enforces
+// the same deadlines, and whether it has already failed permanently.
+func (s *OnErrorStrategy) AttemptPlan(ctx context.Context, locator reference.DockerImageReference) ([]Attempt, error) {
+	alternates, err := s.OnFailure(ctx, locator)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	plan := make([]Attempt, 0, len(alternates))
+	for _, alternate := range alternates {
+		plan = append(plan, Attempt{
+			Ref:       alternate,
+			Timeout:   s.attemptTimeout,
+			Permanent: s.failures[alternate] == FailurePermanent,
+		})
+	}
+	return plan, nil
+}
+
+// AttemptBudget is the number of locations to try for an image and the overall deadline
+// a caller should allow for trying them all.
+type AttemptBudget struct {
+	Alternates int
+	Deadline   time.Duration
+}
+
+// attemptBudget returns the budget for count locations tried for at most timeout each.
+func attemptBudget(count int, timeout time.Duration) AttemptBudget {
+	return AttemptBudget{Alternates: count, Deadline: time.Duration(count) * timeout}
+}
+
+// ResolveWithBudget returns the attempt plan of locator and the budget for executing it, so
+// that callers can set a context deadline covering every location. Locations that already
+// failed permanently are not counted since they will be skipped.
+func (s *OnErrorStrategy) ResolveWithBudget(ctx context.Context, locator reference.DockerImageReference) ([]Attempt, AttemptBudget, error) {
+	plan, err := s.AttemptPlan(ctx, locator)
+	if err != nil {
+		return nil, AttemptBudget{}, err
+	}
+	count := 0
+	for _, attempt := range plan {
+		if !attempt.Permanent {
+			count++
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return plan, attemptBudget(count, s.attemptTimeout), nil
+}
+
+// CopyPlanEntry is an image to copy and the locations to read it from, in priority order.
+type CopyPlanEntry struct {
+	Source  reference.DockerImageReference
+	Targets []reference.DockerImageReference
+}
+
+// CopyPlan resolves the alternates of every image in sources, in order and without
+// duplicates, so that the mirror command can execute the copies without consulting the
+// policies itself.
+func (s *OnErrorStrategy) CopyPlan(ctx context.Context, sources []reference.DockerImageReference) ([]CopyPlanEntry, error) {
+	seen := make(map[reference.DockerImageReference]bool, len(sources))
+	plan := make([]CopyPlanEntry, 0, len(sources))
+	for _, source := range sources {
+		if seen[source] {
+			continue
+		}
+		seen[source] = true
+		targets, err := s.OnFailure(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("unable to plan the copy of %s: %v", source.Exact(), err)
+		}
+		plan = append(plan, CopyPlanEntry{Source: source, Targets: targets})
+	}
+	return plan, nil
+}
+
+// RegistryPlan is the attempts of a tag plan that are made against one registry.
+type RegistryPlan struct {
+	Registry string
+	// Attempts are the locations on Registry, in the order the tags were given.
+	Attempts []Attempt
+}
+
+// TagPlan returns the attempt plans of every tag of repository combined and grouped by
+// registry, so that a caller retrieving many tags can make every attempt against one
+// registry over the same connection before moving on to the next. Registries are ordered
+// by the earliest position any tag would try them at, so the requested registry comes
+// first, followed by the mirrors in the order they would be tried. A caller moves a tag to
+// the next group that lists it once its attempt has failed.
+func (s *OnErrorStrategy) TagPlan(ctx context.Context, repository string, tags []string) ([]RegistryPlan, error) {
+	ref, err := s.parse(repository)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repository %q: %v", repository, err)
+	}
+	if len(ref.Tag) > 0 || len(ref.ID) > 0 {
+		return nil, fmt.Errorf("%q is an image, not a repository", ref.Exact())
+	}
+	type group struct {
+		plan RegistryPlan
+		rank int
+	}
+	var groups []*group
+	byRegistry := make(map[string]*group)
+	for _, tag := range tags {
+		image := ref
+		image.Tag = tag
+		plan, err := s.AttemptPlan(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		for rank, attempt := range plan {
+			registry := attempt.Ref.DockerClientDefaults().Registry
+			g, ok := byRegistry[registry]
+			if !ok {
+				g = &group{plan: RegistryPlan{Registry: registry}, rank: rank}
+				byRegistry[registry] = g
+				groups = append(groups, g)
+			}
+			if rank < g.rank {
+				g.rank = rank
+			}
+			g.plan.Attempts = append(g.plan.Attempts, attempt)
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].rank < groups[j].rank
+	})
+	plans := make([]RegistryPlan, 0, len(groups))
+	for _, g := range groups {
+		plans = append(plans, g.plan)
+	}
+	return plans, nil
+}
@@ -0,0 +1,160 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestAttemptPlan(t *testing.T) {
+	image := mustParse(t, "quay.io/ocp-test/release@"+testDigest)
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected time.Duration
+	}{
+		{name: "default timeout", expected: DefaultAttemptTimeout},
+		{name: "configured timeout", opts: []Option{WithAttemptTimeout(5 * time.Second)}, expected: 5 * time.Second},
+		{name: "invalid timeout", opts: []Option{WithAttemptTimeout(-time.Second)}, expected: DefaultAttemptTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", tt.opts...)
+			plan, err := s.AttemptPlan(context.Background(), image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := []Attempt{
+				{Ref: image, Timeout: tt.expected},
+				{Ref: mustParse(t, "registry.example.com/ocp-test/release@"+testDigest), Timeout: tt.expected},
+				{Ref: mustParse(t, "mirror.example.com/ocp-test/release@"+testDigest), Timeout: tt.expected},
+			}
+			if !reflect.DeepEqual(plan, expected) {
+				t.Errorf("expected %v, got %v", expected, plan)
+			}
+		})
+	}
+}
+
+func TestCopyPlan(t *testing.T) {
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml")
+	release := mustParse(t, "quay.io/ocp-test/release@"+testDigest)
+	installer := mustParse(t, "quay.io/ocp-test/installer:4.5")
+	etcd := mustParse(t, "registry.redhat.io/operators/etcd@"+testDigest)
+	unmirrored := mustParse(t, "docker.io/library/busybox:latest")
+
+	plan, err := s.CopyPlan(context.Background(), []reference.DockerImageReference{release, installer, etcd, release, unmirrored})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		source  reference.DockerImageReference
+		targets []string
+	}{
+		{release, []string{
+			"quay.io/ocp-test/release@" + testDigest,
+			"registry.example.com/ocp-test/release@" + testDigest,
+			"mirror.example.com/ocp-test/release@" + testDigest,
+		}},
+		{installer, []string{"quay.io/ocp-test/installer:4.5", "registry.example.com/ocp-test/installer:4.5"}},
+		{etcd, []string{"registry.redhat.io/operators/etcd@" + testDigest, "registry.example.com/operators/etcd@" + testDigest}},
+		{unmirrored, []string{"docker.io/library/busybox:latest"}},
+	}
+	if len(plan) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %v", len(expected), len(plan), plan)
+	}
+	for i, entry := range plan {
+		if entry.Source != expected[i].source {
+			t.Errorf("%d: expected source %s, got %s", i, expected[i].source.Exact(), entry.Source.Exact())
+		}
+		if got := exactRefs(entry.Targets); !reflect.DeepEqual(got, expected[i].targets) {
+			t.Errorf("%d: expected targets %v, got %v", i, expected[i].targets, got)
+		}
+	}
+
+	if _, err := s.CopyPlan(context.Background(), []reference.DockerImageReference{{ID: testDigest}}); err == nil {
+		t.Errorf("expected an error for an image without a repository")
+	}
+}
+
+func TestAttemptBudget(t *testing.T) {
+	tests := []struct {
+		count    int
+		timeout  time.Duration
+		expected time.Duration
+	}{
+		{count: 0, timeout: DefaultAttemptTimeout, expected: 0},
+		{count: 1, timeout: DefaultAttemptTimeout, expected: 30 * time.Second},
+		{count: 4, timeout: 5 * time.Second, expected: 20 * time.Second},
+	}
+	for _, tt := range tests {
+		if budget := attemptBudget(tt.count, tt.timeout); budget.Alternates != tt.count || budget.Deadline != tt.expected {
+			t.Errorf("%d attempts of %s: expected a deadline of %s, got %+v", tt.count, tt.timeout, tt.expected, budget)
+		}
+	}
+}
+
+func TestResolveWithBudget(t *testing.T) {
+	image := mustParse(t, "quay.io/ocp-test/release@"+testDigest)
+	permanent := func(reference.DockerImageReference, error) FailureClass { return FailurePermanent }
+	s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", WithAttemptTimeout(5*time.Second), WithFailureClassifier(permanent))
+	plan, budget, err := s.ResolveWithBudget(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan) != 3 || budget != (AttemptBudget{Alternates: 3, Deadline: 15 * time.Second}) {
+		t.Errorf("unexpected budget %+v for plan %v", budget, plan)
+	}
+
+	s.RecordFailure(mustParse(t, "mirror.example.com/ocp-test/release@"+testDigest), errors.New("not found"))
+	_, budget, err = s.ResolveWithBudget(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if budget != (AttemptBudget{Alternates: 2, Deadline: 10 * time.Second}) {
+		t.Errorf("expected the permanently failed mirror not to be budgeted, got %+v", budget)
+	}
+}
+
+func TestTagPlan(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp", "first.example.com/ocp", "second.example.com/ocp")),
+		newICSP("conditional", rdm("quay.io/ocp/release", "third.example.com/ocp/release", "second.example.com/mirror/release")),
+	}}
+	client.items[1].Annotations = map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/release": ["4.9"]}`}
+	s := NewICSPOnErrorStrategy(client, "", WithAttemptTimeout(time.Second))
+	plans, err := s.TagPlan(context.Background(), "quay.io/ocp/release", []string{"4.8", "4.9"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var actual []string
+	for _, plan := range plans {
+		var refs []string
+		for _, attempt := range plan.Attempts {
+			if attempt.Timeout != time.Second {
+				t.Errorf("expected the attempt timeout for %s, got %s", attempt.Ref.Exact(), attempt.Timeout)
+			}
+			refs = append(refs, attempt.Ref.Exact())
+		}
+		actual = append(actual, fmt.Sprintf("%s: %v", plan.Registry, refs))
+	}
+	expected := []string{
+		"quay.io: [quay.io/ocp/release:4.8 quay.io/ocp/release:4.9]",
+		"first.example.com: [first.example.com/ocp/release:4.8 first.example.com/ocp/release:4.9]",
+		"second.example.com: [second.example.com/ocp/release:4.8 second.example.com/ocp/release:4.9 second.example.com/mirror/release:4.9]",
+		"third.example.com: [third.example.com/ocp/release:4.9]",
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	if _, err := s.TagPlan(context.Background(), "quay.io/ocp/release:4.8", []string{"4.9"}); err == nil {
+		t.Errorf("expected an error for an image instead of a repository")
+	}
+}
@@ -0,0 +1,195 @@
+package strategy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// normalizePolicies returns icspList with the ambiguities of individual policies resolved,
+// along with a warning for each of them, for each mirror that is likely a mistake and for
+// each cycle of sources and mirrors. The objects in icspList are not modified.
+func normalizePolicies(icspList []operatorv1alpha1.ImageContentSourcePolicy) ([]operatorv1alpha1.ImageContentSourcePolicy, []string) {
+	var warnings []string
+	normalized := make([]operatorv1alpha1.ImageContentSourcePolicy, 0, len(icspList))
+	for i := range icspList {
+		icsp, duplicates := mergeDuplicateSources(&icspList[i])
+		for _, source := range duplicates {
+			warnings = append(warnings, fmt.Sprintf("ImageContentSourcePolicy %s lists source %s more than once, its mirrors have been merged", icsp.Name, source))
+		}
+		for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+			for _, mirror := range sameRegistryMirrors(rdm) {
+				warnings = append(warnings, fmt.Sprintf("ImageContentSourcePolicy %s mirrors source %s to %s on the same registry, which is likely a mistake", icsp.Name, normalizeRepository(rdm.Source), mirror))
+			}
+		}
+		normalized = append(normalized, *icsp)
+	}
+	for _, cycle := range mirrorCycles(normalized) {
+		warnings = append(warnings, fmt.Sprintf("the sources and mirrors of the ImageContentSourcePolicies form a cycle %s, mirrors are not followed so an entry is likely reversed", strings.Join(cycle, " -> ")))
+	}
+	return normalized, warnings
+}
+
+// sameRegistryMirrors returns the mirrors of rdm on the registry of its source but at a
+// different path. Such mirrors are still used, but usually point at the wrong registry.
+func sameRegistryMirrors(rdm operatorv1alpha1.RepositoryDigestMirrors) []string {
+	source := normalizeRepository(rdm.Source)
+	registry := strings.ToLower(strings.SplitN(source, "/", 2)[0])
+	var found []string
+	for _, mirror := range rdm.Mirrors {
+		mirror = normalizeRepository(mirror)
+		if mirror != source && strings.ToLower(strings.SplitN(mirror, "/", 2)[0]) == registry {
+			found = append(found, mirror)
+		}
+	}
+	return found
+}
+
+// mergeDuplicateSources combines the entries of icsp that name the same source into the
+// first of them, appending the mirrors of later entries that are not already present. It
+// returns icsp itself when there is nothing to merge, or a merged copy and the duplicated
+// sources otherwise.
+func mergeDuplicateSources(icsp *operatorv1alpha1.ImageContentSourcePolicy) (*operatorv1alpha1.ImageContentSourcePolicy, []string) {
+	index := make(map[string]int)
+	var duplicates []string
+	var merged []operatorv1alpha1.RepositoryDigestMirrors
+	for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+		source := normalizeRepository(rdm.Source)
+		i, ok := index[source]
+		if !ok {
+			index[source] = len(merged)
+			merged = append(merged, *rdm.DeepCopy())
+			continue
+		}
+		if len(duplicates) == 0 || duplicates[len(duplicates)-1] != source {
+			duplicates = append(duplicates, source)
+		}
+		existing := make(map[string]bool)
+		for _, mirror := range merged[i].Mirrors {
+			existing[normalizeRepository(mirror)] = true
+		}
+		for _, mirror := range rdm.Mirrors {
+			if !existing[normalizeRepository(mirror)] {
+				existing[normalizeRepository(mirror)] = true
+				merged[i].Mirrors = append(merged[i].Mirrors, mirror)
+			}
+		}
+	}
+	if len(duplicates) == 0 {
+		return icsp, nil
+	}
+	copied := icsp.DeepCopy()
+	copied.Spec.RepositoryDigestMirrors = merged
+	return copied, duplicates
+}
+
+// validatePolicies returns an error for the first mirror, annotation or mirror group in
+// icspList that would fail the resolution of a matching image. Invalid policies are
+// reported as a *ValidationError.
+func validatePolicies(icspList []operatorv1alpha1.ImageContentSourcePolicy, parse ReferenceParser) error {
+	if _, err := mirrorGroups(icspList); err != nil {
+		return err
+	}
+	for i := range icspList {
+		if err := validatePolicy(&icspList[i], parse); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithRequiredAnnotation only uses the policies annotated with key set to value, such as
+// mirror.openshift.io/verified=true, so that policies can be staged before they take
+// effect. Overlays are applied regardless.
+func WithRequiredAnnotation(key, value string) Option {
+	return func(s *OnErrorStrategy) {
+		if s.requiredAnnotations == nil {
+			s.requiredAnnotations = make(map[string]string)
+		}
+		s.requiredAnnotations[key] = value
+	}
+}
+
+// requireAnnotations returns the policies in icspList carrying every required annotation.
+func (s *OnErrorStrategy) requireAnnotations(icspList []operatorv1alpha1.ImageContentSourcePolicy) []operatorv1alpha1.ImageContentSourcePolicy {
+	if len(s.requiredAnnotations) == 0 {
+		return icspList
+	}
+	required := make([]operatorv1alpha1.ImageContentSourcePolicy, 0, len(icspList))
+	for _, icsp := range icspList {
+		missing := false
+		for key, value := range s.requiredAnnotations {
+			if actual, ok := icsp.Annotations[key]; !ok || actual != value {
+				klog.V(4).Infof("Ignoring ImageContentSourcePolicy %s without annotation %s=%s", icsp.Name, key, value)
+				missing = true
+				break
+			}
+		}
+		if !missing {
+			required = append(required, icsp)
+		}
+	}
+	return required
+}
+
+// WithPolicyOwner only uses the policies with an owner reference to the object of the
+// given kind and name, such as the resource of the operator managing them, so that
+// policies created by hand are ignored. Overlays are applied regardless.
+func WithPolicyOwner(kind, name string) Option {
+	return func(s *OnErrorStrategy) {
+		s.owners = append(s.owners, metav1.OwnerReference{Kind: kind, Name: name})
+	}
+}
+
+// requireOwners returns the policies in icspList owned by one of the required owners.
+func (s *OnErrorStrategy) requireOwners(icspList []operatorv1alpha1.ImageContentSourcePolicy) []operatorv1alpha1.ImageContentSourcePolicy {
+	if len(s.owners) == 0 {
+		return icspList
+	}
+	owned := make([]operatorv1alpha1.ImageContentSourcePolicy, 0, len(icspList))
+	for _, icsp := range icspList {
+		if s.ownedPolicy(icsp) {
+			owned = append(owned, icsp)
+			continue
+		}
+		klog.V(4).Infof("Ignoring ImageContentSourcePolicy %s without a required owner", icsp.Name)
+	}
+	return owned
+}
+
+// ownedPolicy returns true if icsp has an owner reference to one of the required owners.
+func (s *OnErrorStrategy) ownedPolicy(icsp operatorv1alpha1.ImageContentSourcePolicy) bool {
+	for _, ref := range icsp.OwnerReferences {
+		for _, owner := range s.owners {
+			if ref.Kind == owner.Kind && ref.Name == owner.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithNewestPoliciesFirst orders the loaded policies from the most to the least recently
+// created, so that when several policies declare the same source the mirrors of the newest
+// lead. Policies without a creation timestamp, such as those read from a file, come last
+// in the order they were loaded. With WithKindPrecedence this orders policies of the same
+// kind.
+func WithNewestPoliciesFirst() Option {
+	return func(s *OnErrorStrategy) {
+		s.newestFirst = true
+	}
+}
+
+// orderByCreation stably sorts icspList from the most to the least recently created.
+func orderByCreation(icspList []operatorv1alpha1.ImageContentSourcePolicy) []operatorv1alpha1.ImageContentSourcePolicy {
+	ordered := append([]operatorv1alpha1.ImageContentSourcePolicy(nil), icspList...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[j].CreationTimestamp.Before(&ordered[i].CreationTimestamp)
+	})
+	return ordered
+}
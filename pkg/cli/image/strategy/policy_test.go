@@ -0,0 +1,216 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestDuplicateSourcesInPolicy(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com/ocp/release"),
+			rdm("quay.io/other", "registry.example.com/other"),
+			rdm("quay.io/ocp/release/", "mirror.example.com/ocp/release", "backup.example.com/ocp/release"),
+		),
+	}}
+	warnings := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings))
+	for i := 0; i < 2; i++ {
+		alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release@"+testDigest))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{
+			"quay.io/ocp/release@" + testDigest,
+			"registry.example.com/ocp/release@" + testDigest,
+			"mirror.example.com/ocp/release@" + testDigest,
+			"backup.example.com/ocp/release@" + testDigest,
+		}
+		if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v, got %v", expected, got)
+		}
+		// a second image forces another load to verify the warning is reported once
+		if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/other/image:latest")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !strings.HasPrefix(warnings.String(), "warning: ImageContentSourcePolicy release lists source quay.io/ocp/release more than once") {
+		t.Errorf("unexpected warnings: %q", warnings.String())
+	}
+	if n := strings.Count(warnings.String(), "\n"); n != 1 {
+		t.Errorf("expected a single warning, got %d: %q", n, warnings.String())
+	}
+
+	merged, duplicates := mergeDuplicateSources(&client.items[0])
+	if !reflect.DeepEqual(duplicates, []string{"quay.io/ocp/release"}) {
+		t.Errorf("unexpected duplicates: %v", duplicates)
+	}
+	if len(merged.Spec.RepositoryDigestMirrors) != 2 || len(client.items[0].Spec.RepositoryDigestMirrors) != 3 {
+		t.Errorf("expected a merged copy leaving the original untouched")
+	}
+}
+
+func TestWithRequiredAnnotation(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		withAnnotations(
+			newICSP("verified", rdm("quay.io/ocp/release", "verified.example.com/ocp/release")),
+			map[string]string{"mirror.openshift.io/verified": "true"},
+		),
+		withAnnotations(
+			newICSP("rejected", rdm("quay.io/ocp/release", "rejected.example.com/ocp/release")),
+			map[string]string{"mirror.openshift.io/verified": "false"},
+		),
+		newICSP("unverified", rdm("quay.io/ocp/release", "unverified.example.com/ocp/release")),
+	}}
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+
+	alternates, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alternates) != 4 {
+		t.Errorf("expected every policy to contribute by default, got %v", exactRefs(alternates))
+	}
+
+	s := NewICSPOnErrorStrategy(client, "", WithRequiredAnnotation("mirror.openshift.io/verified", "true"))
+	alternates, err = s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"quay.io/ocp/release:4.8", "verified.example.com/ocp/release:4.8"}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestWithPolicyOwner(t *testing.T) {
+	owned := newICSP("owned", rdm("quay.io/ocp/release", "owned.example.com/ocp/release"))
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "mirror.example.com/v1", Kind: "MirrorConfig", Name: "cluster"}}
+	other := newICSP("other", rdm("quay.io/ocp/release", "other.example.com/ocp/release"))
+	other.OwnerReferences = []metav1.OwnerReference{{APIVersion: "mirror.example.com/v1", Kind: "MirrorConfig", Name: "staging"}}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		owned,
+		other,
+		newICSP("unowned", rdm("quay.io/ocp/release", "unowned.example.com/ocp/release")),
+	}}
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+
+	alternates, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alternates) != 4 {
+		t.Errorf("expected every policy to contribute by default, got %v", exactRefs(alternates))
+	}
+
+	s := NewICSPOnErrorStrategy(client, "", WithPolicyOwner("MirrorConfig", "cluster"))
+	alternates, err = s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"quay.io/ocp/release:4.8", "owned.example.com/ocp/release:4.8"}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestSameRegistryMirrorWarning(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "quay.io/ocp/release-mirror", "registry.example.com/ocp/release")),
+	}}
+	warnings := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings))
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp/release:4.8",
+		"quay.io/ocp/release-mirror:4.8",
+		"registry.example.com/ocp/release:4.8",
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the same-registry mirror to still be returned, got %v", got)
+	}
+	expectedWarning := "warning: ImageContentSourcePolicy release mirrors source quay.io/ocp/release to quay.io/ocp/release-mirror on the same registry"
+	if !strings.HasPrefix(warnings.String(), expectedWarning) {
+		t.Errorf("unexpected warnings: %q", warnings.String())
+	}
+	if n := strings.Count(warnings.String(), "\n"); n != 1 {
+		t.Errorf("expected a single warning, got %d: %q", n, warnings.String())
+	}
+}
+
+func TestSameRegistryMirrors(t *testing.T) {
+	tests := []struct {
+		rdm      operatorv1alpha1.RepositoryDigestMirrors
+		expected []string
+	}{
+		{rdm: rdm("quay.io/ocp/release", "registry.example.com/ocp/release")},
+		{rdm: rdm("quay.io/ocp/release", "quay.io/ocp/release/")},
+		{rdm: rdm("quay.io/ocp/release", "Quay.io/mirror/release"), expected: []string{"Quay.io/mirror/release"}},
+		{rdm: rdm("registry.example.com:5000/ocp", "registry.example.com/ocp")},
+	}
+	for _, tt := range tests {
+		if actual := sameRegistryMirrors(tt.rdm); !reflect.DeepEqual(tt.expected, actual) {
+			t.Errorf("%v: expected %v, got %v", tt.rdm, tt.expected, actual)
+		}
+	}
+}
+
+func TestWithNewestPoliciesFirst(t *testing.T) {
+	older := newICSP("older", rdm("quay.io/ocp/release", "a.example.com/ocp/release", "b.example.com/ocp/release"))
+	older.CreationTimestamp = metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	newer := newICSP("newer", rdm("quay.io/ocp/release", "b.example.com/ocp/release", "c.example.com/ocp/release"))
+	newer.CreationTimestamp = metav1.NewTime(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	undated := newICSP("undated", rdm("quay.io/ocp/release", "d.example.com/ocp/release"))
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{undated, older, newer}}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name: "listed order",
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"d.example.com/ocp/release:4.8",
+				"a.example.com/ocp/release:4.8",
+				"b.example.com/ocp/release:4.8",
+				"c.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			name: "newest first",
+			opts: []Option{WithNewestPoliciesFirst()},
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"b.example.com/ocp/release:4.8",
+				"c.example.com/ocp/release:4.8",
+				"a.example.com/ocp/release:4.8",
+				"d.example.com/ocp/release:4.8",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", tt.opts...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
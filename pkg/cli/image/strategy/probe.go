@@ -0,0 +1,109 @@
+package strategy
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// DefaultProbeConcurrency is the number of mirrors probed at the same time unless
+// WithProbeConcurrency sets another limit.
+const DefaultProbeConcurrency = 4
+
+// Prober checks whether the image at ref can currently be retrieved, returning an error
+// if it cannot.
+type Prober interface {
+	Probe(ctx context.Context, ref reference.DockerImageReference) error
+}
+
+// ProberFunc adapts a function to the Prober interface.
+type ProberFunc func(ctx context.Context, ref reference.DockerImageReference) error
+
+// Probe calls f.
+func (f ProberFunc) Probe(ctx context.Context, ref reference.DockerImageReference) error {
+	return f(ctx, ref)
+}
+
+// WithProbe probes the mirrors of each image when its alternates are resolved and orders
+// the reachable mirrors ahead of the unreachable ones, which are still returned in case
+// the probe was wrong. The requested image is not moved, and is only probed with
+// WithUnreachableSourceOmitted.
+func WithProbe(prober Prober) Option {
+	return func(s *OnErrorStrategy) {
+		s.prober = prober
+	}
+}
+
+// WithProbeConcurrency limits the number of probes in flight at the same time to limit,
+// so that images with many mirrors do not overwhelm the network. Values below one
+// probe the mirrors one at a time.
+func WithProbeConcurrency(limit int) Option {
+	return func(s *OnErrorStrategy) {
+		if limit < 1 {
+			limit = 1
+		}
+		s.probeConcurrency = limit
+	}
+}
+
+// WithUnreachableSourceOmitted also probes the requested image when it has mirrors, and
+// omits it from the alternates if it cannot be reached, so that callers in a disconnected
+// environment do not spend an attempt on it. It has no effect without WithProbe.
+func WithUnreachableSourceOmitted() Option {
+	return func(s *OnErrorStrategy) {
+		s.probeSource = true
+	}
+}
+
+// probeMirrors probes the mirrors of r and moves those that could not be reached after
+// those that could, then omits the requested image if it was probed and is unreachable.
+// Mirrors that are not probed before ctx is done keep their place.
+func (s *OnErrorStrategy) probeMirrors(ctx context.Context, r *resolution) {
+	if s.prober == nil || len(r.alternates) < 2 {
+		return
+	}
+	probed := r.alternates[1:]
+	if s.probeSource && !r.sourceOmitted {
+		probed = r.alternates
+	}
+	unreachable := make(map[reference.DockerImageReference]bool, len(probed))
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, s.probeConcurrency)
+	for i, alternate := range probed {
+		ref := alternate.Ref
+		if i == 0 && len(probed) == len(r.alternates) {
+			// The requested image may be unqualified, and is probed where it refers to.
+			ref = qualifiedRef(ref)
+		}
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			// A probe cut short by ctx says nothing about the mirror, which keeps its place.
+			if err := s.prober.Probe(ctx, ref); err != nil && ctx.Err() == nil {
+				klog.V(4).Infof("Mirror %s is unreachable: %v", ref.Exact(), err)
+				lock.Lock()
+				unreachable[ref] = true
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	image := r.alternates[0].Ref
+	r.promoteMirrors(func(alternate Alternate) bool {
+		return !unreachable[alternate.Ref]
+	})
+	if unreachable[qualifiedRef(image)] && s.probeSource && !r.sourceOmitted {
+		r.sourceOmitted = true
+		r.skipped = append(r.skipped, Skipped{Ref: image, Reason: SkipUnreachable})
+	}
+}
@@ -0,0 +1,155 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestProbeOrdering(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("policy", rdm("quay.io/ocp/release", "down.example.com/ocp/release", "up.example.com/ocp/release")),
+	}}
+	prober := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		if ref.Registry == "down.example.com" {
+			return fmt.Errorf("connection refused")
+		}
+		return nil
+	})
+	s := NewICSPOnErrorStrategy(client, "", WithProbe(prober))
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"quay.io/ocp/release:4.8",
+		"up.example.com/ocp/release:4.8",
+		"down.example.com/ocp/release:4.8",
+	}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestProbeConcurrency(t *testing.T) {
+	var mirrors []string
+	for i := 0; i < 10; i++ {
+		mirrors = append(mirrors, fmt.Sprintf("mirror%d.example.com/ocp/release", i))
+	}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("policy", rdm("quay.io/ocp/release", mirrors...)),
+	}}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected int
+	}{
+		{name: "default", expected: DefaultProbeConcurrency},
+		{name: "limited", opts: []Option{WithProbeConcurrency(2)}, expected: 2},
+		{name: "serial", opts: []Option{WithProbeConcurrency(0)}, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var inFlight, peak, calls int
+			prober := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+				lock.Lock()
+				inFlight++
+				calls++
+				if inFlight > peak {
+					peak = inFlight
+				}
+				lock.Unlock()
+				time.Sleep(10 * time.Millisecond)
+				lock.Lock()
+				inFlight--
+				lock.Unlock()
+				return nil
+			})
+			s := NewICSPOnErrorStrategy(client, "", append(test.opts, WithProbe(prober))...)
+			if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8")); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if calls != len(mirrors) {
+				t.Errorf("expected every mirror to be probed once, got %d probes", calls)
+			}
+			if peak > test.expected {
+				t.Errorf("expected at most %d concurrent probes, got %d", test.expected, peak)
+			}
+		})
+	}
+}
+
+func TestUnreachableSourceOmitted(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("policy",
+			rdm("quay.io/ocp/release", "mirror.example.com/ocp/release"),
+			rdm("docker.io/library/busybox", "mirror.example.com/library/busybox"),
+		),
+	}}
+	sourceDown := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		if ref.Registry == "quay.io" || ref.Registry == "docker.io" {
+			return fmt.Errorf("no route to host")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		image    string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "unreachable source omitted",
+			image:    "quay.io/ocp/release:4.8",
+			opts:     []Option{WithProbe(sourceDown), WithUnreachableSourceOmitted()},
+			expected: []string{"mirror.example.com/ocp/release:4.8"},
+		},
+		{
+			name:  "reachable source kept",
+			image: "quay.io/ocp/release:4.8",
+			opts: []Option{WithProbe(ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+				return nil
+			})), WithUnreachableSourceOmitted()},
+			expected: []string{"quay.io/ocp/release:4.8", "mirror.example.com/ocp/release:4.8"},
+		},
+		{
+			name:     "source kept without the option",
+			image:    "quay.io/ocp/release:4.8",
+			opts:     []Option{WithProbe(sourceDown)},
+			expected: []string{"quay.io/ocp/release:4.8", "mirror.example.com/ocp/release:4.8"},
+		},
+		{
+			name:     "unqualified source probed where it refers to",
+			image:    "busybox:latest",
+			opts:     []Option{WithProbe(sourceDown), WithUnreachableSourceOmitted()},
+			expected: []string{"mirror.example.com/library/busybox:latest"},
+		},
+		{
+			name:     "source without mirrors kept",
+			image:    "quay.io/other/image:latest",
+			opts:     []Option{WithProbe(sourceDown), WithUnreachableSourceOmitted()},
+			expected: []string{"quay.io/other/image:latest"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", test.opts...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, test.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package strategy
+
+import (
+	"context"
+	// Registers sha512 so that digest.Parse accepts sha512 digests, which are rewritten onto
+	// mirrors like any other digest.
+	_ "crypto/sha512"
+	"fmt"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ReferenceParser parses an image pull spec. ParseReference is used unless another parser
+// is provided with WithReferenceParser.
+type ReferenceParser func(spec string) (reference.DockerImageReference, error)
+
+// WithReferenceParser parses mirrors and the images of workloads with parse, for registries
+// whose hosts or paths reference.Parse does not understand. The parser must return the
+// registry host in Registry and the repository path in Namespace and Name so that the
+// result can be matched against sources.
+func WithReferenceParser(parse ReferenceParser) Option {
+	return func(s *OnErrorStrategy) {
+		s.parse = parse
+	}
+}
+
+// ParseReference parses spec like reference.Parse, additionally accepting registries
+// addressed by a bracketed IPv6 literal, e.g. [fd00::1]:5000/ns/image:tag.
+func ParseReference(spec string) (reference.DockerImageReference, error) {
+	if !strings.HasPrefix(spec, "[") {
+		return reference.Parse(spec)
+	}
+	i := strings.Index(spec, "/")
+	end := strings.Index(spec, "]")
+	if i < 0 || end < 0 || end > i {
+		return reference.DockerImageReference{}, fmt.Errorf("invalid reference %q, a registry host must be followed by a repository", spec)
+	}
+	host := spec[:i]
+	if rest := host[end+1:]; len(rest) > 0 && !strings.HasPrefix(rest, ":") {
+		return reference.DockerImageReference{}, fmt.Errorf("invalid registry host %q", host)
+	}
+	// parse the repository against a placeholder host, since the parser rejects IPv6 hosts
+	ref, err := reference.Parse("registry.invalid" + spec[i:])
+	if err != nil {
+		return reference.DockerImageReference{}, err
+	}
+	ref.Registry = host
+	return ref, nil
+}
+
+// validateLocator rejects references that cannot be matched against a source. A bare
+// digest has no repository, and reference.Parse reads "<algorithm>:<hex>" as an image named
+// after the algorithm tagged with the hex, which would otherwise be matched as if it were a
+// name.
+func validateLocator(locator reference.DockerImageReference) error {
+	if len(locator.Name) == 0 {
+		if len(locator.ID) > 0 {
+			return fmt.Errorf("image %q has a digest but no repository, mirrors can only be resolved for a repository", locator.ID)
+		}
+		return fmt.Errorf("image %q has no repository, mirrors can only be resolved for a repository", locator.Exact())
+	}
+	if len(locator.Registry) == 0 && len(locator.Namespace) == 0 && len(locator.ID) == 0 {
+		if _, err := digest.Parse(locator.Name + ":" + locator.Tag); err == nil {
+			return fmt.Errorf("image %q is a digest without a repository, mirrors can only be resolved for a repository", locator.Name+":"+locator.Tag)
+		}
+	}
+	return nil
+}
+
+// localTransports are the prefixes of references to images in an OCI image layout on disk
+// rather than in a registry.
+var localTransports = []string{"oci:", "oci-archive:"}
+
+// isLocalReference returns true if spec refers to an image outside of any registry.
+func isLocalReference(spec string) bool {
+	for _, transport := range localTransports {
+		if strings.HasPrefix(spec, transport) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvePullSpec returns spec followed by the pull specs of its mirrors. References to an
+// OCI image layout, such as oci:/path/to/layout:tag, are not in any registry and are
+// returned unchanged without mirrors.
+func (s *OnErrorStrategy) ResolvePullSpec(ctx context.Context, spec string) ([]string, error) {
+	if isLocalReference(spec) {
+		klog.V(4).Infof("No mirrors apply to %s, it is not in a registry", spec)
+		return []string{spec}, nil
+	}
+	ref, err := s.parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image %q: %v", spec, err)
+	}
+	alternates, err := s.OnFailure(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]string, 0, len(alternates))
+	for _, alternate := range alternates {
+		specs = append(specs, alternate.Exact())
+	}
+	return specs, nil
+}
@@ -0,0 +1,198 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestDigestOnlyReference(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("catchall", rdm("sha256", "registry.example.com/sha256")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	tests := []struct {
+		name    string
+		locator reference.DockerImageReference
+	}{
+		{name: "digest without a name", locator: reference.DockerImageReference{ID: testDigest}},
+		{name: "digest parsed as a name", locator: mustParse(t, testDigest)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.OnFailure(context.Background(), tt.locator)
+			if err == nil || !strings.Contains(err.Error(), "without a repository") && !strings.Contains(err.Error(), "but no repository") {
+				t.Fatalf("expected a missing repository error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), testDigest) {
+				t.Errorf("expected the error to identify the digest: %v", err)
+			}
+			if _, err := s.Explain(context.Background(), tt.locator); err == nil {
+				t.Errorf("expected Explain to reject the reference")
+			}
+		})
+	}
+	if client.calls != 0 {
+		t.Errorf("expected policies not to be loaded for an invalid reference")
+	}
+}
+
+const testSHA512Digest = "sha512:" +
+	"0e1e21ecf105ec853d24d728867ad70613c21663a4693074b2a3619c1bd39d66" +
+	"b588c33723bb466c72424e80e3ca63c249078ab347bab9428500e7ee43059d0d"
+
+func TestSHA512Digest(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp-test/release", "registry.example.com/ocp-test/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp-test/release@"+testSHA512Digest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alternates) != 2 {
+		t.Fatalf("expected a mirror, got %v", exactRefs(alternates))
+	}
+	for _, alternate := range alternates {
+		if alternate.ID != testSHA512Digest || len(alternate.Tag) > 0 {
+			t.Errorf("expected %s to carry the sha512 digest unchanged", alternate.Exact())
+		}
+	}
+
+	pinned, err := s.ResolveForDigest(context.Background(), mustParse(t, "quay.io/ocp-test/release:latest"), testSHA512Digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pinned[1].Exact() != "registry.example.com/ocp-test/release@"+testSHA512Digest {
+		t.Errorf("unexpected pinned mirror %s", pinned[1].Exact())
+	}
+
+	if _, err := s.OnFailure(context.Background(), mustParse(t, testSHA512Digest)); err == nil || !strings.Contains(err.Error(), "without a repository") {
+		t.Errorf("expected a bare sha512 digest to be rejected, got %v", err)
+	}
+}
+
+// parseUppercasePaths accepts repositories with uppercase characters, which reference.Parse
+// rejects, by lowercasing them.
+func parseUppercasePaths(spec string) (reference.DockerImageReference, error) {
+	i := strings.Index(spec, "/")
+	if i < 0 {
+		return reference.Parse(spec)
+	}
+	return reference.Parse(spec[:i] + strings.ToLower(spec[i:]))
+}
+
+func TestReferenceParser(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("legacy", rdm("registry.corp.example.com/team/app", "LegacyMirror.example.com/Team/App", "registry.example.com/team/app")),
+	}}
+	locator := mustParse(t, "registry.corp.example.com/team/app:v1")
+
+	if _, err := NewICSPOnErrorStrategy(client, "").OnFailure(context.Background(), locator); err == nil {
+		t.Fatalf("expected the default parser to reject the mirror")
+	}
+
+	alternates, err := NewICSPOnErrorStrategy(client, "", WithReferenceParser(parseUppercasePaths)).OnFailure(context.Background(), locator)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"registry.corp.example.com/team/app:v1",
+		"LegacyMirror.example.com/team/app:v1",
+		"registry.example.com/team/app:v1",
+	}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestIPLiteralHosts(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("ipv4", rdm("10.0.0.1:5000/ns/img", "10.0.0.2:5000/mirror/img")),
+		newICSP("ipv6", rdm("[fd00::1]:5000/ns/img", "[fd00::2]:5000/mirror/img", "[fd00::3]/mirror/img")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image:    "10.0.0.1:5000/ns/img:v1",
+			expected: []string{"10.0.0.1:5000/ns/img:v1", "10.0.0.2:5000/mirror/img:v1"},
+		},
+		{
+			image:    "[fd00::1]:5000/ns/img@" + testDigest,
+			expected: []string{"[fd00::1]:5000/ns/img@" + testDigest, "[fd00::2]:5000/mirror/img@" + testDigest, "[fd00::3]/mirror/img@" + testDigest},
+		},
+		{
+			image:    "[fd00::1]:5001/ns/img:v1",
+			expected: []string{"[fd00::1]:5001/ns/img:v1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := ParseReference(tt.image)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			alternates, err := s.OnFailure(context.Background(), ref)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+
+	ref, err := ParseReference("[fd00::2]:5000/mirror/img:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Registry != "[fd00::2]:5000" || ref.Namespace != "mirror" || ref.Name != "img" || ref.Tag != "v1" {
+		t.Errorf("unexpected components %#v", ref)
+	}
+	if host := registryHost(ref.Registry); host != "fd00::2" {
+		t.Errorf("unexpected host %q", host)
+	}
+	for _, invalid := range []string{"[fd00::1]:5000", "[fd00::1/img", "[fd00::1]x/img"} {
+		if _, err := ParseReference(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestResolvePullSpec(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("catchall", rdm("oci", "registry.example.com/oci"), rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	tests := []struct {
+		spec     string
+		expected []string
+	}{
+		{spec: "oci:/var/lib/layouts/release:4.8", expected: []string{"oci:/var/lib/layouts/release:4.8"}},
+		{spec: "oci:release", expected: []string{"oci:release"}},
+		{spec: "oci-archive:/tmp/release.tar", expected: []string{"oci-archive:/tmp/release.tar"}},
+		{spec: "quay.io/ocp/release:4.8", expected: []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			specs, err := s.ResolvePullSpec(context.Background(), tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.expected, specs) {
+				t.Errorf("expected %v, got %v", tt.expected, specs)
+			}
+		})
+	}
+	if client.calls != 1 {
+		t.Errorf("expected policies to be loaded only for the registry reference, listed %d times", client.calls)
+	}
+}
@@ -0,0 +1,105 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// registriesConf is the subset of the containers-registries.conf(5) format, as rendered
+// onto nodes by the machine-config operator, that describes mirrors.
+type registriesConf struct {
+	Registries []registriesConfRegistry `toml:"registry"`
+}
+
+type registriesConfRegistry struct {
+	Prefix   string                 `toml:"prefix"`
+	Location string                 `toml:"location"`
+	Insecure bool                   `toml:"insecure"`
+	Mirrors  []registriesConfMirror `toml:"mirror"`
+}
+
+type registriesConfMirror struct {
+	Location string `toml:"location"`
+	Insecure bool   `toml:"insecure"`
+}
+
+// WithRegistriesConf loads mirrors from the [[registry]] blocks of a registries.conf file
+// instead of, or in addition to, ImageContentSourcePolicies. This covers clusters where
+// the mirror configuration only exists in a MachineConfig. Mirrors marked insecure in
+// the file are marked insecure as if they were passed to WithInsecureMirrors.
+func WithRegistriesConf(path string) Option {
+	return func(s *OnErrorStrategy) {
+		if s.insecureConf == nil {
+			s.insecureConf = make(map[string][]string)
+		}
+		s.sources = append(s.sources, func(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+			data, err := readFileLimited(path, s.decode.maxSize)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read registries configuration %s: %v", path, err)
+			}
+			icsp, insecure, err := parseRegistriesConf(path, data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse registries configuration %s: %v", path, err)
+			}
+			s.insecureConf[path] = insecure
+			return []operatorv1alpha1.ImageContentSourcePolicy{*icsp}, nil
+		})
+	}
+}
+
+// parseRegistriesConf converts the registries with mirrors in data into a single policy
+// with the given name. When a registry is reached through a location other than its
+// prefix, that location is tried after its mirrors, matching the container runtime. The
+// hosts of the mirrors marked insecure are returned along with the policy.
+func parseRegistriesConf(name string, data []byte) (*operatorv1alpha1.ImageContentSourcePolicy, []string, error) {
+	conf := &registriesConf{}
+	if _, err := toml.Decode(string(data), conf); err != nil {
+		return nil, nil, err
+	}
+	icsp := &operatorv1alpha1.ImageContentSourcePolicy{}
+	icsp.APIVersion = operatorv1alpha1.GroupVersion.String()
+	icsp.Kind = "ImageContentSourcePolicy"
+	icsp.Name = name
+	var insecure []string
+	for i, registry := range conf.Registries {
+		source := registry.Prefix
+		if len(source) == 0 {
+			source = registry.Location
+		}
+		if len(source) == 0 {
+			return nil, nil, fmt.Errorf("registry %d has neither a prefix nor a location", i)
+		}
+		var mirrors []string
+		for _, mirror := range registry.Mirrors {
+			mirrors = append(mirrors, mirror.Location)
+			if mirror.Insecure {
+				insecure = append(insecure, locationHost(mirror.Location))
+			}
+		}
+		if len(registry.Location) > 0 && normalizeRepository(registry.Location) != normalizeRepository(source) {
+			mirrors = append(mirrors, registry.Location)
+			if registry.Insecure {
+				insecure = append(insecure, locationHost(registry.Location))
+			}
+		}
+		if len(mirrors) == 0 {
+			continue
+		}
+		icsp.Spec.RepositoryDigestMirrors = append(icsp.Spec.RepositoryDigestMirrors, operatorv1alpha1.RepositoryDigestMirrors{
+			Source:  source,
+			Mirrors: mirrors,
+		})
+	}
+	return icsp, insecure, nil
+}
+
+// locationHost returns the registry host, including any port, of a registries.conf
+// location.
+func locationHost(location string) string {
+	return strings.ToLower(strings.SplitN(location, "/", 2)[0])
+}
@@ -0,0 +1,117 @@
+package strategy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWithRegistriesConf(t *testing.T) {
+	fromICSP := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml")
+	fromConf := NewICSPOnErrorStrategy(nil, "", WithRegistriesConf("testdata/registries.conf"))
+	for _, image := range []string{
+		"quay.io/ocp-test/release@" + testDigest,
+		"quay.io/ocp-test/other@" + testDigest,
+		"registry.redhat.io/operators/etcd@" + testDigest,
+		"registry.access.redhat.com/ubi8/ubi@" + testDigest,
+	} {
+		ref := mustParse(t, image)
+		expected, err := fromICSP.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := fromConf.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", image, exactRefs(expected), exactRefs(got))
+		}
+	}
+}
+
+func TestParseRegistriesConfPrefix(t *testing.T) {
+	data := []byte(`
+[[registry]]
+  prefix = "example.com/foo"
+  location = "internal.example.com/bar"
+
+  [[registry.mirror]]
+    location = "mirror.example.com/bar"
+`)
+	icsp, _, err := parseRegistriesConf("test", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := rdm("example.com/foo", "mirror.example.com/bar", "internal.example.com/bar")
+	if len(icsp.Spec.RepositoryDigestMirrors) != 1 || !reflect.DeepEqual(icsp.Spec.RepositoryDigestMirrors[0], expected) {
+		t.Errorf("expected %v, got %v", expected, icsp.Spec.RepositoryDigestMirrors)
+	}
+
+	if _, _, err := parseRegistriesConf("test", []byte("[[registry]]\ninsecure = true\n")); err == nil {
+		t.Errorf("expected an error for a registry without a prefix or location")
+	}
+	if _, _, err := parseRegistriesConf("test", []byte("[[registry\n")); err == nil {
+		t.Errorf("expected an error for invalid TOML")
+	}
+}
+
+func TestParseRegistriesConfInsecure(t *testing.T) {
+	data := []byte(`
+[[registry]]
+  prefix = "example.com/foo"
+  location = "internal.example.com:5000/bar"
+  insecure = true
+
+  [[registry.mirror]]
+    location = "secure.example.com/bar"
+
+  [[registry.mirror]]
+    location = "Insecure.example.com/bar"
+    insecure = true
+`)
+	_, insecure, err := parseRegistriesConf("test", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"insecure.example.com", "internal.example.com:5000"}; !reflect.DeepEqual(insecure, expected) {
+		t.Errorf("expected insecure hosts %v, got %v", expected, insecure)
+	}
+
+	dir, err := ioutil.TempDir("", "registries-conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "registries.conf")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewICSPOnErrorStrategy(nil, "", WithRegistriesConf(path))
+	snapshot, err := NewICSPOnErrorStrategy(nil, "", WithRegistriesConf(path)).Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]bool{
+		"example.com/foo/app@" + testDigest:               false,
+		"secure.example.com/bar/app@" + testDigest:        false,
+		"Insecure.example.com/bar/app@" + testDigest:      true,
+		"internal.example.com:5000/bar/app@" + testDigest: true,
+	}
+	for name, s := range map[string]*OnErrorStrategy{"strategy": s, "snapshot": snapshot} {
+		alternates, err := s.OnFailureWithSources(context.Background(), mustParse(t, "example.com/foo/app@"+testDigest))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := make(map[string]bool)
+		for _, alternate := range alternates {
+			got[alternate.Ref.Exact()] = alternate.Insecure
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package strategy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ReleaseContentProvider retrieves the manifests bundled in a release payload.
+type ReleaseContentProvider interface {
+	// ReleaseManifests returns the contents of the release-manifests directory of the
+	// release image at ref, keyed by file name.
+	ReleaseManifests(ctx context.Context, ref reference.DockerImageReference) (map[string][]byte, error)
+}
+
+// WithReleasePolicy loads the ImageContentSourcePolicy, ImageDigestMirrorSet and
+// ImageTagMirrorSet manifests bundled in the release image at ref, so that a disconnected
+// mirror can be driven by the policy shipped with the release it mirrors. Other manifests
+// in the payload are ignored.
+func WithReleasePolicy(ref reference.DockerImageReference, provider ReleaseContentProvider) Option {
+	return func(s *OnErrorStrategy) {
+		s.sources = append(s.sources, func(ctx context.Context) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+			return readICSPsFromRelease(ctx, ref, provider, s.decode)
+		})
+	}
+}
+
+func readICSPsFromRelease(ctx context.Context, ref reference.DockerImageReference, provider ReleaseContentProvider, opts decodeOptions) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	manifests, err := provider.ReleaseManifests(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the manifests of release image %s: %v", ref.Exact(), err)
+	}
+	names := make([]string, 0, len(manifests))
+	for name := range manifests {
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml", ".json":
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var icspList []operatorv1alpha1.ImageContentSourcePolicy
+	for _, name := range names {
+		data, err := readLimited(bytes.NewReader(manifests[name]), opts.maxSize)
+		if err != nil {
+			return nil, fmt.Errorf("manifest %s of release image %s %v", name, ref.Exact(), err)
+		}
+		loaded, err := parsePolicyManifests(data, opts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse manifest %s of release image %s: %v", name, ref.Exact(), err)
+		}
+		icspList = append(icspList, loaded...)
+	}
+	return icspList, nil
+}
+
+// UnmirroredComponents returns the tags of a release's image-references that have no
+// mirror, in the order they are listed, so that the components that still need mirroring
+// can be reported before installing from a mirror. The images are resolved as a batch by
+// ResolveStream with at most concurrency lookups in flight. Tags that do not reference a
+// DockerImage are ignored, and the first error resolving a component is returned.
+func (s *OnErrorStrategy) UnmirroredComponents(ctx context.Context, references *imagev1.ImageStream, concurrency int) ([]imagev1.TagReference, error) {
+	var tags []imagev1.TagReference
+	var images []reference.DockerImageReference
+	for _, tag := range references.Spec.Tags {
+		if tag.From == nil || tag.From.Kind != "DockerImage" {
+			continue
+		}
+		image, err := s.parse(tag.From.Name)
+		if err != nil {
+			return nil, fmt.Errorf("component %s has an invalid image %q: %v", tag.Name, tag.From.Name, err)
+		}
+		tags = append(tags, tag)
+		images = append(images, image)
+	}
+	mirrored := make(map[reference.DockerImageReference]bool, len(images))
+	var firstErr error
+	for result := range s.ResolveStream(ctx, images, concurrency) {
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unable to resolve %s: %v", result.Image.Exact(), result.Err)
+			}
+			continue
+		}
+		mirrored[result.Image] = hasMirror(result.Image, result.Alternates)
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	var unmirrored []imagev1.TagReference
+	for i, tag := range tags {
+		if !mirrored[images[i]] {
+			unmirrored = append(unmirrored, tag)
+		}
+	}
+	return unmirrored, nil
+}
+
+// imageMirrors is an entry of an ImageDigestMirrorSet or ImageTagMirrorSet.
+type imageMirrors struct {
+	Source             string   `json:"source"`
+	Mirrors            []string `json:"mirrors,omitempty"`
+	MirrorSourcePolicy string   `json:"mirrorSourcePolicy,omitempty"`
+}
+
+// imageDigestMirrorSet is the subset of a config.openshift.io/v1 ImageDigestMirrorSet
+// needed to resolve mirrors.
+type imageDigestMirrorSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		ImageDigestMirrors []imageMirrors `json:"imageDigestMirrors"`
+	} `json:"spec"`
+}
+
+// imageTagMirrorSet is the subset of a config.openshift.io/v1 ImageTagMirrorSet needed to
+// resolve mirrors.
+type imageTagMirrorSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              struct {
+		ImageTagMirrors []imageMirrors `json:"imageTagMirrors"`
+	} `json:"spec"`
+}
+
+// parsePolicyManifests returns the policies declared in data by ImageContentSourcePolicy,
+// ImageDigestMirrorSet and ImageTagMirrorSet documents, skipping documents of any other kind.
+func parsePolicyManifests(data []byte, opts decodeOptions) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	var icspList []operatorv1alpha1.ImageContentSourcePolicy
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for i := 0; ; i++ {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		if err := checkDocumentCount(i+1, opts); err != nil {
+			return nil, err
+		}
+		icsp, err := decodePolicy(doc, opts)
+		if err != nil {
+			return nil, fmt.Errorf("document %d: %v", i, err)
+		}
+		if icsp != nil {
+			icspList = append(icspList, *icsp)
+		}
+	}
+	return icspList, nil
+}
+
+// decodePolicy decodes a policy document of any of the supported kinds, or returns nil if
+// doc is of another kind. Mirror sets are converted to an ImageContentSourcePolicy that
+// keeps the kind of the document, so that policies can be ordered by kind, and that lists
+// its NeverContactSource entries in NeverContactSourceAnnotation.
+func decodePolicy(doc []byte, opts decodeOptions) (*operatorv1alpha1.ImageContentSourcePolicy, error) {
+	unmarshal := yaml.Unmarshal
+	if opts.strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+		return nil, err
+	}
+	var meta metav1.ObjectMeta
+	var entries []imageMirrors
+	switch typeMeta.Kind {
+	case "ImageContentSourcePolicy":
+		var icsp operatorv1alpha1.ImageContentSourcePolicy
+		if err := unmarshal(doc, &icsp); err != nil {
+			return nil, err
+		}
+		return &icsp, nil
+	case "ImageDigestMirrorSet":
+		var idms imageDigestMirrorSet
+		if err := unmarshal(doc, &idms); err != nil {
+			return nil, err
+		}
+		meta, entries = idms.ObjectMeta, idms.Spec.ImageDigestMirrors
+	case "ImageTagMirrorSet":
+		var itms imageTagMirrorSet
+		if err := unmarshal(doc, &itms); err != nil {
+			return nil, err
+		}
+		meta, entries = itms.ObjectMeta, itms.Spec.ImageTagMirrors
+	default:
+		return nil, nil
+	}
+	icsp := &operatorv1alpha1.ImageContentSourcePolicy{ObjectMeta: meta}
+	icsp.APIVersion = operatorv1alpha1.GroupVersion.String()
+	icsp.Kind = typeMeta.Kind
+	var neverContact []string
+	for _, mirrors := range entries {
+		icsp.Spec.RepositoryDigestMirrors = append(icsp.Spec.RepositoryDigestMirrors, operatorv1alpha1.RepositoryDigestMirrors{
+			Source:  mirrors.Source,
+			Mirrors: mirrors.Mirrors,
+		})
+		if mirrors.MirrorSourcePolicy == NeverContactSource {
+			neverContact = append(neverContact, mirrors.Source)
+		}
+	}
+	if len(neverContact) > 0 {
+		value, err := json.Marshal(neverContact)
+		if err != nil {
+			return nil, err
+		}
+		annotations := make(map[string]string, len(meta.Annotations)+1)
+		for key, existing := range meta.Annotations {
+			annotations[key] = existing
+		}
+		annotations[NeverContactSourceAnnotation] = string(value)
+		icsp.Annotations = annotations
+	}
+	return icsp, nil
+}
@@ -0,0 +1,140 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	imagev1 "github.com/openshift/api/image/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+type fakeReleaseContentProvider struct {
+	manifests map[string]map[string][]byte
+}
+
+func (f *fakeReleaseContentProvider) ReleaseManifests(ctx context.Context, ref reference.DockerImageReference) (map[string][]byte, error) {
+	manifests, ok := f.manifests[ref.Exact()]
+	if !ok {
+		return nil, fmt.Errorf("release %s not found", ref.Exact())
+	}
+	return manifests, nil
+}
+
+const releaseICSP = `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: release
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/openshift-release-dev/ocp-release
+    mirrors:
+    - registry.example.com/ocp/release
+`
+
+const releaseIDMS = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+---
+apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: payload
+spec:
+  imageDigestMirrors:
+  - source: quay.io/openshift-release-dev/ocp-v4.0-art-dev
+    mirrors:
+    - registry.example.com/ocp/payload
+`
+
+func TestWithReleasePolicy(t *testing.T) {
+	release := mustParse(t, "quay.io/openshift-release-dev/ocp-release:4.8.0-x86_64")
+	provider := &fakeReleaseContentProvider{manifests: map[string]map[string][]byte{
+		release.Exact(): {
+			"0000_50_mirror_icsp.yaml":   []byte(releaseICSP),
+			"0000_50_mirror_idms.yaml":   []byte(releaseIDMS),
+			"image-references":           []byte(`{"kind": "ImageStream"}`),
+			"0000_00_cluster-version.md": []byte("not a manifest"),
+		},
+	}}
+	s := NewICSPOnErrorStrategy(nil, "", WithReleasePolicy(release, provider))
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image:    "quay.io/openshift-release-dev/ocp-release@" + testDigest,
+			expected: []string{"quay.io/openshift-release-dev/ocp-release@" + testDigest, "registry.example.com/ocp/release@" + testDigest},
+		},
+		{
+			image:    "quay.io/openshift-release-dev/ocp-v4.0-art-dev@" + testDigest,
+			expected: []string{"quay.io/openshift-release-dev/ocp-v4.0-art-dev@" + testDigest, "registry.example.com/ocp/payload@" + testDigest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+
+	missing := NewICSPOnErrorStrategy(nil, "", WithReleasePolicy(mustParse(t, "quay.io/openshift-release-dev/ocp-release:4.9.0-x86_64"), provider))
+	if _, err := missing.OnFailure(context.Background(), mustParse(t, tests[0].image)); err == nil {
+		t.Errorf("expected an error for a release that cannot be read")
+	}
+
+	invalid := &fakeReleaseContentProvider{manifests: map[string]map[string][]byte{
+		release.Exact(): {"0000_50_mirror_icsp.yaml": []byte("kind: ImageContentSourcePolicy\nspec: []\n")},
+	}}
+	if err := NewICSPOnErrorStrategy(nil, "", WithReleasePolicy(release, invalid)).Init(context.Background()); err == nil {
+		t.Errorf("expected an error for an invalid policy manifest")
+	}
+}
+
+func TestUnmirroredComponents(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("payload", rdm("quay.io/openshift-release-dev/ocp-v4.0-art-dev", "registry.example.com/ocp/payload")),
+	}}
+	component := func(name, image string) imagev1.TagReference {
+		return imagev1.TagReference{Name: name, From: &corev1.ObjectReference{Kind: "DockerImage", Name: image}}
+	}
+	references := &imagev1.ImageStream{}
+	references.Spec.Tags = []imagev1.TagReference{
+		component("cli", "quay.io/openshift-release-dev/ocp-v4.0-art-dev@"+testDigest),
+		component("must-gather", "quay.io/other/must-gather@"+testDigest),
+		{Name: "stream", From: &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream:latest"}},
+		component("installer", "quay.io/openshift-release-dev/ocp-v4.0-art-dev@"+testDigest),
+		component("tools", "docker.io/library/tools:latest"),
+	}
+	s := NewICSPOnErrorStrategy(client, "")
+	unmirrored, err := s.UnmirroredComponents(context.Background(), references, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, tag := range unmirrored {
+		names = append(names, tag.Name)
+	}
+	if expected := []string{"must-gather", "tools"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	references.Spec.Tags = append(references.Spec.Tags, component("pinned", "quay.io/other/pinned:latest"))
+	if _, err := NewICSPOnErrorStrategy(client, "", WithRequiredDigest()).UnmirroredComponents(context.Background(), references, 2); err == nil {
+		t.Errorf("expected an error for a component that cannot be resolved")
+	}
+	references.Spec.Tags = append(references.Spec.Tags, component("invalid", "Invalid//Image"))
+	if _, err := s.UnmirroredComponents(context.Background(), references, 2); err == nil {
+		t.Errorf("expected an error for an invalid component image")
+	}
+}
@@ -0,0 +1,61 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// WithHTTPClient replaces http.DefaultClient for fetching policies from a URL. The
+// default client goes through the proxy named by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *OnErrorStrategy) {
+		s.httpClient = client
+	}
+}
+
+// WithProxy fetches policies from a URL through the proxy at proxyURL regardless of the
+// environment, or without a proxy if proxyURL is nil. It replaces the client set by
+// WithHTTPClient.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(s *OnErrorStrategy) {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		s.httpClient = &http.Client{Transport: transport}
+	}
+}
+
+// isPolicyURL returns true if icspFile names an http or https location rather than a file.
+func isPolicyURL(icspFile string) bool {
+	return strings.HasPrefix(icspFile, "http://") || strings.HasPrefix(icspFile, "https://")
+}
+
+// readICSPsFromURL fetches the ImageContentSourcePolicy documents served at url.
+func readICSPsFromURL(ctx context.Context, client *http.Client, url string, opts decodeOptions) ([]operatorv1alpha1.ImageContentSourcePolicy, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ImageContentSourcePolicy URL %s: %v", url, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch ImageContentSourcePolicy from %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch ImageContentSourcePolicy from %s: server returned %s", url, resp.Status)
+	}
+	data, err := readLimited(resp.Body, opts.maxSize)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read ImageContentSourcePolicy from %s: %v", url, err)
+	}
+	icspList, err := parseICSPs(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse ImageContentSourcePolicy from %s: %v", url, err)
+	}
+	return icspList, nil
+}
@@ -0,0 +1,95 @@
+package strategy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const remotePolicy = `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: remote
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - registry.example.com/ocp/release
+`
+
+func TestPolicyFromURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/policy.yaml":
+			w.Write([]byte(remotePolicy))
+		case "/invalid.yaml":
+			w.Write([]byte("spec: ["))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("served", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(nil, server.URL+"/policy.yaml", WithHTTPClient(server.Client()))
+		alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}
+		if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected %v, got %v", expected, actual)
+		}
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/missing.yaml", expected: "server returned 404 Not Found"},
+		{path: "/invalid.yaml", expected: "unable to parse ImageContentSourcePolicy from " + server.URL + "/invalid.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(nil, server.URL+tt.path, WithHTTPClient(server.Client()))
+			_, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestPolicyThroughProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		if r.URL.Host != "policies.example.com" || r.URL.Path != "/policy.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(remotePolicy))
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewICSPOnErrorStrategy(nil, "http://policies.example.com/policy.yaml", WithProxy(proxyURL))
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if !reflect.DeepEqual(proxied, []string{"http://policies.example.com/policy.yaml"}) {
+		t.Errorf("expected the policy to be fetched through the proxy, got %v", proxied)
+	}
+}
@@ -0,0 +1,172 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// Marshal serializes the policies the strategy resolves against, after normalization, as
+// a stream of YAML documents that can be loaded again as a file. Each policy keeps the
+// kind it was loaded as, ImageContentSourcePolicy, ImageDigestMirrorSet or
+// ImageTagMirrorSet. Fields populated by the server are dropped.
+func (s *OnErrorStrategy) Marshal(ctx context.Context) ([]byte, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return marshalICSPs(icspList)
+}
+
+// MarshalImageDigestMirrorSet serializes the effective policy as a single
+// ImageDigestMirrorSet named name, for migrating from ImageContentSourcePolicies. Since an
+// ImageDigestMirrorSet only applies to images pulled by digest, sources with tag
+// conditions are left out, as are redirects and sources excluded from mirroring, which it
+// cannot express, with a warning for each.
+func (s *OnErrorStrategy) MarshalImageDigestMirrorSet(ctx context.Context, name string) ([]byte, error) {
+	policy, err := s.EffectivePolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idms := imageDigestMirrorSet{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "config.openshift.io/v1", Kind: "ImageDigestMirrorSet"},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}
+	idms.Spec.ImageDigestMirrors = []imageMirrors{}
+	for _, source := range policy.Sources {
+		switch {
+		case len(source.RedirectTo) > 0:
+			s.warn(fmt.Sprintf("the redirect of %s to %s cannot be expressed by an ImageDigestMirrorSet and was left out", source.Source, source.RedirectTo))
+			continue
+		case source.Excluded:
+			s.warn(fmt.Sprintf("the exclusion of %s from mirroring cannot be expressed by an ImageDigestMirrorSet and was left out", source.Source))
+			continue
+		case source.TagPatterns != nil:
+			s.warn(fmt.Sprintf("the mirrors of %s only apply to tags and were left out of the ImageDigestMirrorSet", source.Source))
+			continue
+		}
+		if len(source.Mirrors) > 0 {
+			idms.Spec.ImageDigestMirrors = append(idms.Spec.ImageDigestMirrors, imageMirrors{Source: source.Source, Mirrors: source.Mirrors})
+		}
+	}
+	data, err := yaml.Marshal(idms)
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize ImageDigestMirrorSet %s: %v", name, err)
+	}
+	return data, nil
+}
+
+// WriteCSV writes the mappings of the policies the strategy resolves against, after
+// normalization, to w as CSV with a header, one row per source and mirror in load order,
+// for review in a spreadsheet.
+func (s *OnErrorStrategy) WriteCSV(ctx context.Context, w io.Writer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return err
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"source", "mirror", "policyName", "kind"}); err != nil {
+		return err
+	}
+	for i := range icspList {
+		icsp := &icspList[i]
+		for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+			for _, mirror := range rdm.Mirrors {
+				if err := out.Write([]string{rdm.Source, mirror, icsp.Name, policyKind(icsp)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// marshalICSPs serializes icspList as a stream of YAML documents, each of the kind it was
+// loaded as, so that ImageDigestMirrorSets and ImageTagMirrorSets keep their semantics
+// when loaded again.
+func marshalICSPs(icspList []operatorv1alpha1.ImageContentSourcePolicy) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for i := range icspList {
+		icsp := icspList[i].DeepCopy()
+		icsp.ResourceVersion = ""
+		icsp.UID = ""
+		icsp.SelfLink = ""
+		icsp.Generation = 0
+		icsp.ManagedFields = nil
+		kind := policyKind(icsp)
+		var data []byte
+		var err error
+		switch kind {
+		case "ImageDigestMirrorSet", "ImageTagMirrorSet":
+			data, err = marshalMirrorSet(icsp, kind)
+		default:
+			icsp.APIVersion = operatorv1alpha1.GroupVersion.String()
+			icsp.Kind = kind
+			data, err = yaml.Marshal(icsp)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to serialize %s %s: %v", kind, icsp.Name, err)
+		}
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// marshalMirrorSet serializes icsp as the ImageDigestMirrorSet or ImageTagMirrorSet it was
+// decoded from, declaring the sources of its NeverContactSourceAnnotation with
+// mirrorSourcePolicy again.
+func marshalMirrorSet(icsp *operatorv1alpha1.ImageContentSourcePolicy, kind string) ([]byte, error) {
+	neverContact, err := neverContactSources(icsp)
+	if err != nil {
+		return nil, err
+	}
+	meta := icsp.ObjectMeta
+	if _, ok := meta.Annotations[NeverContactSourceAnnotation]; ok {
+		meta.Annotations = make(map[string]string, len(icsp.Annotations))
+		for key, value := range icsp.Annotations {
+			if key != NeverContactSourceAnnotation {
+				meta.Annotations[key] = value
+			}
+		}
+		if len(meta.Annotations) == 0 {
+			meta.Annotations = nil
+		}
+	}
+	entries := []imageMirrors{}
+	for _, rdm := range icsp.Spec.RepositoryDigestMirrors {
+		entry := imageMirrors{Source: rdm.Source, Mirrors: rdm.Mirrors}
+		if neverContact[normalizeRepository(rdm.Source)] {
+			entry.MirrorSourcePolicy = NeverContactSource
+		}
+		entries = append(entries, entry)
+	}
+	typeMeta := metav1.TypeMeta{APIVersion: "config.openshift.io/v1", Kind: kind}
+	if kind == "ImageTagMirrorSet" {
+		itms := imageTagMirrorSet{TypeMeta: typeMeta, ObjectMeta: meta}
+		itms.Spec.ImageTagMirrors = entries
+		return yaml.Marshal(itms)
+	}
+	idms := imageDigestMirrorSet{TypeMeta: typeMeta, ObjectMeta: meta}
+	idms.Spec.ImageDigestMirrors = entries
+	return yaml.Marshal(idms)
+}
@@ -0,0 +1,196 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	original := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml")
+	data, err := original.Marshal(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reloaded := NewICSPOnErrorStrategy(nil, "", WithInlinePolicy(string(data)), WithStrictDecoding())
+	for _, image := range []string{
+		"quay.io/ocp-test/release@" + testDigest,
+		"quay.io/ocp-test/other:4.5",
+		"registry.redhat.io/operators/etcd@" + testDigest,
+		"docker.io/library/busybox:latest",
+	} {
+		ref := mustParse(t, image)
+		expected, err := original.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := reloaded.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error reloading %s: %v", data, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", image, exactRefs(expected), exactRefs(got))
+		}
+	}
+}
+
+func TestMarshalKindsRoundTrip(t *testing.T) {
+	policies := mixedKindPolicies + `---
+apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: never-contact
+spec:
+  imageDigestMirrors:
+  - source: quay.io/ocp/installer
+    mirrors:
+    - idms.example.com/ocp/installer
+    mirrorSourcePolicy: NeverContactSource
+`
+	data, err := NewICSPOnErrorStrategy(nil, "", WithInlinePolicy(policies)).Marshal(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	icspList, err := parseICSPs(data, decodeOptions{strict: true})
+	if err != nil {
+		t.Fatalf("unexpected error parsing %s: %v", data, err)
+	}
+	var kinds []string
+	for i := range icspList {
+		kinds = append(kinds, policyKind(&icspList[i])+"/"+icspList[i].Name)
+	}
+	expectedKinds := []string{"ImageContentSourcePolicy/icsp", "ImageDigestMirrorSet/idms", "ImageTagMirrorSet/itms", "ImageDigestMirrorSet/never-contact"}
+	if !reflect.DeepEqual(kinds, expectedKinds) {
+		t.Errorf("expected %v, got %v", expectedKinds, kinds)
+	}
+
+	for _, opts := range [][]Option{nil, {WithDigestMirrorsOnly()}} {
+		original := NewICSPOnErrorStrategy(nil, "", append(opts, WithInlinePolicy(policies))...)
+		reloaded := NewICSPOnErrorStrategy(nil, "", append(opts, WithInlinePolicy(string(data)), WithStrictDecoding())...)
+		for _, image := range []string{
+			"quay.io/ocp/release:4.8",
+			"quay.io/ocp/release@" + testDigest,
+			"quay.io/ocp/installer@" + testDigest,
+		} {
+			ref := mustParse(t, image)
+			expected, err := original.OnFailure(context.Background(), ref)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got, err := reloaded.OnFailure(context.Background(), ref)
+			if err != nil {
+				t.Fatalf("unexpected error reloading %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("%s: expected %v, got %v", image, exactRefs(expected), exactRefs(got))
+			}
+		}
+	}
+}
+
+func TestMarshalClusterPolicies(t *testing.T) {
+	icsp := withGeneration(newICSP("cluster", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")), 4)
+	icsp.TypeMeta.Kind = ""
+	icsp.ResourceVersion = "1234"
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{icsp}}
+	data, err := NewICSPOnErrorStrategy(client, "").Marshal(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	icspList, err := parseICSPs(data, decodeOptions{strict: true})
+	if err != nil {
+		t.Fatalf("unexpected error parsing %s: %v", data, err)
+	}
+	if len(icspList) != 1 || icspList[0].Name != "cluster" || icspList[0].ResourceVersion != "" || icspList[0].Generation != 0 {
+		t.Errorf("unexpected policies: %#v", icspList)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("cluster", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "", WithInlinePolicy(mixedKindPolicies), WithClusterMerge())
+	buf := &bytes.Buffer{}
+	if err := s.WriteCSV(context.Background(), buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `source,mirror,policyName,kind
+quay.io/ocp/release,icsp.example.com/ocp/release,icsp,ImageContentSourcePolicy
+quay.io/ocp/release,idms.example.com/ocp/release,idms,ImageDigestMirrorSet
+quay.io/ocp/release,itms.example.com/ocp/release,itms,ImageTagMirrorSet
+quay.io/ocp/release,registry.example.com/ocp/release,cluster,ImageContentSourcePolicy
+`
+	if got := buf.String(); got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestMarshalImageDigestMirrorSet(t *testing.T) {
+	tagged := newICSP("tagged", rdm("quay.io/ocp/tagged", "registry.example.com/ocp/tagged"))
+	tagged.Annotations = map[string]string{TagPatternsAnnotation: `{"quay.io/ocp/tagged": ["4.*"]}`}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com/ocp/release"),
+			rdm("quay.io/ocp", "registry.example.com/ocp"),
+		),
+		newICSP("other", rdm("quay.io/ocp/release", "backup.example.com/ocp/release", "registry.example.com/ocp/release")),
+		newICSP("excluded", rdm("quay.io/ocp/secret", NeverMirror)),
+		tagged,
+	}}
+	warnings := &bytes.Buffer{}
+	original := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings))
+	data, err := original.MarshalImageDigestMirrorSet(context.Background(), "migrated")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  creationTimestamp: null
+  name: migrated
+spec:
+  imageDigestMirrors:
+  - mirrors:
+    - registry.example.com/ocp/release
+    - mirror.example.com/ocp/release
+    - backup.example.com/ocp/release
+    source: quay.io/ocp/release
+  - mirrors:
+    - registry.example.com/ocp
+    source: quay.io/ocp
+`
+	if string(data) != expected {
+		t.Errorf("unexpected ImageDigestMirrorSet:\n%s", data)
+	}
+	for _, warning := range []string{"exclusion of quay.io/ocp/secret", "mirrors of quay.io/ocp/tagged only apply to tags"} {
+		if !strings.Contains(warnings.String(), warning) {
+			t.Errorf("expected a warning containing %q, got %q", warning, warnings.String())
+		}
+	}
+
+	reloaded := NewICSPOnErrorStrategy(nil, "", WithInlinePolicy(string(data)), WithStrictDecoding())
+	for _, image := range []string{
+		"quay.io/ocp/release@" + testDigest,
+		"quay.io/ocp/installer@" + testDigest,
+		"quay.io/ocp/tagged@" + testDigest,
+		"docker.io/library/busybox@" + testDigest,
+	} {
+		ref := mustParse(t, image)
+		want, err := original.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := reloaded.OnFailure(context.Background(), ref)
+		if err != nil {
+			t.Fatalf("unexpected error reloading %s: %v", data, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", image, exactRefs(want), exactRefs(got))
+		}
+	}
+}
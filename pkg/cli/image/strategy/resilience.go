@@ -0,0 +1,78 @@
+package strategy
+
+import (
+	"fmt"
+)
+
+// WithMinimumMirrors requires every source matching an image to provide at least count
+// mirrors, so that an image is not left depending on a single location. Mirrors removed by
+// the allowed or denied registries do not count, while mirrors also provided by an earlier
+// source do. Sources with fewer mirrors are reported as warnings, or fail the resolution
+// when refuse is set.
+func WithMinimumMirrors(count int, refuse bool) Option {
+	return func(s *OnErrorStrategy) {
+		s.minimumMirrors = count
+		s.refuseUnderMirrored = refuse
+	}
+}
+
+// checkMinimumMirrors applies the minimum mirror requirement to r. The caller must hold
+// s.lock.
+func (s *OnErrorStrategy) checkMinimumMirrors(r *resolution) error {
+	if s.minimumMirrors <= 0 {
+		return nil
+	}
+	counts := make(map[policyEntry]int, len(r.sources))
+	for _, alternate := range r.alternates[1:] {
+		counts[policyEntry{policy: alternate.Policy, source: alternate.Source}]++
+	}
+	for _, skipped := range r.skipped {
+		if skipped.Reason == SkipDuplicate {
+			counts[policyEntry{policy: skipped.Policy, source: skipped.Source}]++
+		}
+	}
+	for _, entry := range r.sources {
+		count := counts[entry]
+		if count >= s.minimumMirrors {
+			continue
+		}
+		message := fmt.Sprintf("source %s in ImageContentSourcePolicy %s provides %d mirrors for %s, at least %d are required", entry.source, entry.policy, count, r.alternates[0].Ref.Exact(), s.minimumMirrors)
+		if s.refuseUnderMirrored {
+			return fmt.Errorf("refusing to resolve mirrors: %s", message)
+		}
+		s.warn(message)
+	}
+	return nil
+}
+
+// WithSourceMirrorLimit returns at most limit of the mirrors contributed by source, in the
+// order they would otherwise be tried, for sources with many mirrors that are rarely worth
+// trying. Other sources are unaffected. The limit applies after mirrors are filtered and
+// checked against WithMinimumMirrors, and values below one keep a single mirror.
+func WithSourceMirrorLimit(source string, limit int) Option {
+	return func(s *OnErrorStrategy) {
+		if limit < 1 {
+			limit = 1
+		}
+		if s.sourceLimits == nil {
+			s.sourceLimits = make(map[string]int)
+		}
+		s.sourceLimits[normalizeRepository(source)] = limit
+	}
+}
+
+// limitSourceMirrors drops the mirrors of r beyond the limit of their source.
+func (s *OnErrorStrategy) limitSourceMirrors(r *resolution) {
+	if len(s.sourceLimits) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	r.filterMirrors(func(alternate Alternate) bool {
+		limit, ok := s.sourceLimits[alternate.Source]
+		if !ok {
+			return true
+		}
+		counts[alternate.Source]++
+		return counts[alternate.Source] <= limit
+	}, SkipLimited)
+}
@@ -0,0 +1,110 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestMinimumMirrors(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("single", rdm("quay.io/ocp/single", "registry.example.com/ocp/single")),
+		newICSP("double", rdm("quay.io/ocp/double", "registry.example.com/ocp/double", "mirror.example.com/ocp/double")),
+	}}
+	single := mustParse(t, "quay.io/ocp/single:latest")
+	double := mustParse(t, "quay.io/ocp/double:latest")
+
+	t.Run("warn", func(t *testing.T) {
+		warnings := &bytes.Buffer{}
+		s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings), WithMinimumMirrors(2, false))
+		alternates, err := s.OnFailure(context.Background(), single)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(alternates) != 2 {
+			t.Errorf("expected the mirror to still be returned, got %v", exactRefs(alternates))
+		}
+		if !strings.Contains(warnings.String(), "quay.io/ocp/single in ImageContentSourcePolicy single provides 1 mirrors") {
+			t.Errorf("expected a warning identifying the source, got %q", warnings.String())
+		}
+	})
+
+	t.Run("refuse", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(client, "", WithMinimumMirrors(2, true))
+		_, err := s.OnFailure(context.Background(), single)
+		if err == nil || !strings.Contains(err.Error(), "quay.io/ocp/single") {
+			t.Fatalf("expected an error identifying the under-mirrored source, got %v", err)
+		}
+		if _, err := s.OnFailure(context.Background(), double); err != nil {
+			t.Errorf("unexpected error for a sufficiently mirrored source: %v", err)
+		}
+	})
+
+	t.Run("filtered mirrors do not count", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(client, "", WithMinimumMirrors(2, true), WithDeniedMirrorRegistries("mirror.example.com"))
+		if _, err := s.OnFailure(context.Background(), double); err == nil {
+			t.Errorf("expected an error once a mirror is filtered")
+		}
+	})
+}
+
+func TestWithSourceMirrorLimit(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "a.example.com/ocp/release", "b.example.com/ocp/release", "c.example.com/ocp/release"),
+			rdm("quay.io/ocp", "d.example.com/ocp", "e.example.com/ocp"),
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "", WithSourceMirrorLimit("quay.io/ocp/release/", 1), WithMinimumMirrors(2, true))
+	tests := []struct {
+		image    string
+		expected []string
+	}{
+		{
+			image: "quay.io/ocp/release:4.8",
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"a.example.com/ocp/release:4.8",
+				"d.example.com/ocp/release:4.8",
+				"e.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			image: "quay.io/ocp/installer:4.8",
+			expected: []string{
+				"quay.io/ocp/installer:4.8",
+				"d.example.com/ocp/installer:4.8",
+				"e.example.com/ocp/installer:4.8",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	explanation, err := s.Explain(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var limited []string
+	for _, skipped := range explanation.Skipped {
+		if skipped.Reason == SkipLimited {
+			limited = append(limited, skipped.Ref.Exact())
+		}
+	}
+	if expected := []string{"b.example.com/ocp/release:4.8", "c.example.com/ocp/release:4.8"}; !reflect.DeepEqual(limited, expected) {
+		t.Errorf("expected %v to be skipped for the limit, got %v", expected, limited)
+	}
+}
@@ -0,0 +1,67 @@
+package strategy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ResolutionRecord is one line of the resolution log.
+type ResolutionRecord struct {
+	Time       time.Time `json:"time"`
+	Image      string    `json:"image"`
+	Alternates []string  `json:"alternates"`
+	Policies   []string  `json:"policies"`
+	// Duration is the time spent loading policies and resolving the image.
+	Duration time.Duration `json:"durationNanoseconds"`
+	Error    string        `json:"error,omitempty"`
+	// CorrelationID is the ID attached to the context of the request with WithCorrelationID.
+	CorrelationID string `json:"correlationID,omitempty"`
+}
+
+// WithResolutionLog writes a ResolutionRecord as a line of JSON to w each time the
+// alternates of an image are resolved, for ingestion by log pipelines. Images served from
+// the cache are not logged again.
+func WithResolutionLog(w io.Writer) Option {
+	return func(s *OnErrorStrategy) {
+		s.resolutionLog = w
+	}
+}
+
+// logResolution writes a record for the resolution of image. The caller must hold s.lock.
+func (s *OnErrorStrategy) logResolution(ctx context.Context, image string, started time.Time, r *resolution, err error) {
+	if s.resolutionLog == nil {
+		return
+	}
+	record := ResolutionRecord{
+		Time:       started,
+		Image:      image,
+		Alternates: []string{},
+		Policies:   []string{},
+		Duration:   time.Since(started),
+
+		CorrelationID: CorrelationID(ctx),
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if r != nil {
+		for _, ref := range r.refs() {
+			record.Alternates = append(record.Alternates, ref.Exact())
+		}
+		for _, policy := range r.matched {
+			record.Policies = append(record.Policies, policy.Name)
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		klog.V(2).Infof("Unable to serialize the resolution of %s: %v", image, err)
+		return
+	}
+	if _, err := s.resolutionLog.Write(append(data, '\n')); err != nil {
+		klog.V(2).Infof("Unable to write the resolution of %s: %v", image, err)
+	}
+}
@@ -0,0 +1,112 @@
+package strategy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithResolutionLog(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	log := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithResolutionLog(log))
+	for _, image := range []string{"quay.io/ocp/release:4.8", "quay.io/ocp/release:4.8", "docker.io/library/busybox:latest"} {
+		if _, err := s.OnFailure(context.Background(), mustParse(t, image)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	client.err = fmt.Errorf("server unavailable")
+	if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.9")); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a record per resolution, got %d:\n%s", len(records), log.String())
+	}
+	for _, record := range records {
+		for _, field := range []string{"time", "image", "alternates", "policies", "durationNanoseconds"} {
+			if _, ok := record[field]; !ok {
+				t.Errorf("expected field %q in %v", field, record)
+			}
+		}
+	}
+	if !reflect.DeepEqual(records[0]["alternates"], []interface{}{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}) {
+		t.Errorf("unexpected alternates %v", records[0]["alternates"])
+	}
+	if !reflect.DeepEqual(records[0]["policies"], []interface{}{"release"}) {
+		t.Errorf("unexpected policies %v", records[0]["policies"])
+	}
+	if !reflect.DeepEqual(records[1]["policies"], []interface{}{}) {
+		t.Errorf("expected no policies for an unmatched image, got %v", records[1]["policies"])
+	}
+	if records[2]["error"] == nil {
+		t.Errorf("expected the failed resolution to record its error: %v", records[2])
+	}
+}
+
+func TestResolutionLogCorrelationID(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp", "registry.example.com/ocp")),
+	}}
+	log := &bytes.Buffer{}
+	s := NewICSPOnErrorStrategy(client, "", WithResolutionLog(log))
+	images := map[string]string{
+		"job-1": "quay.io/ocp/release:4.8",
+		"job-2": "quay.io/ocp/installer:4.8",
+	}
+	var wg sync.WaitGroup
+	for id, image := range images {
+		ctx := WithCorrelationID(context.Background(), id)
+		ref := mustParse(t, image)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.OnFailure(ctx, ref); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/console:4.8")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	seen := make(map[string]string)
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		var record ResolutionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		seen[record.Image] = record.CorrelationID
+	}
+	expected := map[string]string{
+		"quay.io/ocp/release:4.8":   "job-1",
+		"quay.io/ocp/installer:4.8": "job-2",
+		"quay.io/ocp/console:4.8":   "",
+	}
+	if !reflect.DeepEqual(expected, seen) {
+		t.Errorf("expected correlation IDs %v, got %v", expected, seen)
+	}
+	if id := CorrelationID(context.Background()); id != "" {
+		t.Errorf("expected no correlation ID, got %q", id)
+	}
+}
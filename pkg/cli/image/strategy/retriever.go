@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/distribution"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/library-go/pkg/image/registryclient"
+)
+
+// RetrieveFunc retrieves content from repo, which holds ref. The ref passed is either the
+// requested image or one of its mirrors, with the same tag or digest.
+type RetrieveFunc func(ctx context.Context, repo distribution.Repository, ref reference.DockerImageReference) error
+
+// MirrorRetriever adapts the strategy to the retrieve path of a registryclient, so that
+// content is retrieved from the mirrors of an image when the image itself fails.
+//
+// Retrieve resolves the alternates of the requested image with OnFailureWithSources and
+// tries them in that order until one succeeds, so the requested image is tried first only
+// when the strategy keeps it first, last when it is retained after its mirrors, and never
+// when the strategy omits it, such as for a blocked or NeverContactSource source. Each
+// failed attempt is passed to RecordFailure and the successful one to RecordSuccess, so
+// that adaptive ordering and attempt plans reflect the outcome. The strategy is never
+// consulted again once an attempt succeeds.
+type MirrorRetriever struct {
+	// Strategy resolves the alternates of each image.
+	Strategy *OnErrorStrategy
+	// Retriever opens repositories, and is usually a *registryclient.Context.
+	Retriever registryclient.RepositoryRetriever
+	// Insecure allows every repository to be opened over plain HTTP, while mirrors marked
+	// with WithInsecureMirrors are always allowed to be.
+	Insecure bool
+}
+
+// Retrieve calls fn with the repository of each alternate of imageRef in turn until fn
+// succeeds, and returns the reference that succeeded. If every attempt fails, the error
+// of the last attempt is returned. An error resolving the alternates is returned as is.
+func (r *MirrorRetriever) Retrieve(ctx context.Context, imageRef reference.DockerImageReference, fn RetrieveFunc) (reference.DockerImageReference, error) {
+	alternates, err := r.Strategy.OnFailureWithSources(ctx, imageRef)
+	if err != nil {
+		return reference.DockerImageReference{}, err
+	}
+	var lastErr error
+	for _, alternate := range alternates {
+		ref := alternate.Ref
+		if err := ctx.Err(); err != nil {
+			return reference.DockerImageReference{}, err
+		}
+		if lastErr = r.attempt(ctx, ref, r.Insecure || alternate.Insecure, fn); lastErr == nil {
+			r.Strategy.RecordSuccess(imageRef, ref)
+			return ref, nil
+		}
+		class := r.Strategy.RecordFailure(ref, lastErr)
+		klog.V(4).Infof("Unable to retrieve %s from %s (%s): %v", imageRef.Exact(), ref.Exact(), class, lastErr)
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no locations to retrieve %s from", imageRef.Exact())
+	}
+	return reference.DockerImageReference{}, lastErr
+}
+
+// attempt opens the repository of ref, allowing plain HTTP if insecure, and calls fn with it.
+func (r *MirrorRetriever) attempt(ctx context.Context, ref reference.DockerImageReference, insecure bool, fn RetrieveFunc) error {
+	ref = ref.DockerClientDefaults()
+	repo, err := r.Retriever.Repository(ctx, ref.RegistryURL(), ref.RepositoryName(), insecure)
+	if err != nil {
+		return err
+	}
+	return fn(ctx, repo, ref)
+}
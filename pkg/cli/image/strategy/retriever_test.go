@@ -0,0 +1,187 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/docker/distribution"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+type fakeRepository struct {
+	distribution.Repository
+	name string
+}
+
+type fakeRepositoryRetriever struct {
+	opened      []string
+	insecure    []string
+	unreachable map[string]bool
+}
+
+func (f *fakeRepositoryRetriever) Repository(ctx context.Context, registry *url.URL, repoName string, insecure bool) (distribution.Repository, error) {
+	name := registry.Host + "/" + repoName
+	f.opened = append(f.opened, name)
+	if insecure {
+		f.insecure = append(f.insecure, name)
+	}
+	if f.unreachable[registry.Host] {
+		return nil, errors.New("connection refused")
+	}
+	return &fakeRepository{name: name}, nil
+}
+
+func TestMirrorRetriever(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release", "second.example.com/ocp/release", "third.example.com/ocp/release")),
+	}}
+	image := "quay.io/ocp/release@" + testDigest
+	tests := []struct {
+		name        string
+		unreachable map[string]bool
+		failing     map[string]bool
+		expected    string
+		attempted   []string
+		opened      []string
+	}{
+		{
+			name:      "requested image succeeds",
+			expected:  image,
+			attempted: []string{image},
+			opened:    []string{"quay.io/ocp/release"},
+		},
+		{
+			name:      "mirrors in order",
+			failing:   map[string]bool{image: true, "first.example.com/ocp/release@" + testDigest: true},
+			expected:  "second.example.com/ocp/release@" + testDigest,
+			attempted: []string{image, "first.example.com/ocp/release@" + testDigest, "second.example.com/ocp/release@" + testDigest},
+			opened:    []string{"quay.io/ocp/release", "first.example.com/ocp/release", "second.example.com/ocp/release"},
+		},
+		{
+			name:        "unreachable mirror skipped",
+			unreachable: map[string]bool{"quay.io": true, "first.example.com": true},
+			expected:    "second.example.com/ocp/release@" + testDigest,
+			attempted:   []string{"second.example.com/ocp/release@" + testDigest},
+			opened:      []string{"quay.io/ocp/release", "first.example.com/ocp/release", "second.example.com/ocp/release"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retriever := &fakeRepositoryRetriever{unreachable: tt.unreachable}
+			r := &MirrorRetriever{Strategy: NewICSPOnErrorStrategy(client, ""), Retriever: retriever}
+			var attempted []string
+			ref, err := r.Retrieve(context.Background(), mustParse(t, image), func(ctx context.Context, repo distribution.Repository, ref reference.DockerImageReference) error {
+				if name := repo.(*fakeRepository).name; name != ref.AsRepository().Exact() {
+					t.Errorf("expected the repository of %s, got %s", ref.Exact(), name)
+				}
+				attempted = append(attempted, ref.Exact())
+				if tt.failing[ref.Exact()] {
+					return httpStatusError(503)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ref.Exact() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, ref.Exact())
+			}
+			if !reflect.DeepEqual(attempted, tt.attempted) {
+				t.Errorf("expected attempts %v, got %v", tt.attempted, attempted)
+			}
+			if !reflect.DeepEqual(retriever.opened, tt.opened) {
+				t.Errorf("expected repositories %v, got %v", tt.opened, retriever.opened)
+			}
+			if stats := r.Strategy.SuccessStats(); stats[ref.Registry] != 1 {
+				t.Errorf("expected a success to be recorded for %s, got %v", ref.Registry, stats)
+			}
+		})
+	}
+}
+
+func TestMirrorRetrieverAllFail(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release")),
+	}}
+	r := &MirrorRetriever{Strategy: NewICSPOnErrorStrategy(client, ""), Retriever: &fakeRepositoryRetriever{}}
+	var attempted []string
+	_, err := r.Retrieve(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"), func(ctx context.Context, repo distribution.Repository, ref reference.DockerImageReference) error {
+		attempted = append(attempted, ref.Exact())
+		return httpStatusError(len(attempted))
+	})
+	if err != httpStatusError(2) {
+		t.Errorf("expected the error of the last attempt, got %v", err)
+	}
+	expected := []string{"quay.io/ocp/release:4.8", "first.example.com/ocp/release:4.8"}
+	if !reflect.DeepEqual(attempted, expected) {
+		t.Errorf("expected attempts %v, got %v", expected, attempted)
+	}
+}
+
+func TestMirrorRetrieverInsecureMirrors(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release", "second.example.com/ocp/release")),
+	}}
+	retriever := &fakeRepositoryRetriever{}
+	r := &MirrorRetriever{Strategy: NewICSPOnErrorStrategy(client, "", WithInsecureMirrors("first.example.com")), Retriever: retriever}
+	_, err := r.Retrieve(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"), func(ctx context.Context, repo distribution.Repository, ref reference.DockerImageReference) error {
+		return httpStatusError(503)
+	})
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if expected := []string{"first.example.com/ocp/release"}; !reflect.DeepEqual(retriever.insecure, expected) {
+		t.Errorf("expected only %v to be opened insecurely, got %v", expected, retriever.insecure)
+	}
+}
+
+func TestMirrorRetrieverSourcePlacement(t *testing.T) {
+	neverContact := newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release"))
+	neverContact.Annotations = map[string]string{NeverContactSourceAnnotation: `["quay.io/ocp/release"]`}
+	plain := newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release"))
+	tests := []struct {
+		name     string
+		policy   operatorv1alpha1.ImageContentSourcePolicy
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "never contact source",
+			policy:   neverContact,
+			expected: []string{"first.example.com/ocp/release"},
+		},
+		{
+			name:     "blocked source",
+			policy:   plain,
+			opts:     []Option{WithBlockedRegistries("quay.io")},
+			expected: []string{"first.example.com/ocp/release"},
+		},
+		{
+			name:     "source retained",
+			policy:   neverContact,
+			opts:     []Option{WithSourceRetained()},
+			expected: []string{"first.example.com/ocp/release", "quay.io/ocp/release"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{tt.policy}}
+			retriever := &fakeRepositoryRetriever{}
+			r := &MirrorRetriever{Strategy: NewICSPOnErrorStrategy(client, "", tt.opts...), Retriever: retriever}
+			_, err := r.Retrieve(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"), func(ctx context.Context, repo distribution.Repository, ref reference.DockerImageReference) error {
+				return httpStatusError(503)
+			})
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if !reflect.DeepEqual(retriever.opened, tt.expected) {
+				t.Errorf("expected repositories %v, got %v", tt.expected, retriever.opened)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package strategy
+
+import (
+	"fmt"
+
+	"k8s.io/klog/v2"
+)
+
+// mirrorRewrite replaces the repository prefix of generated mirrors.
+type mirrorRewrite struct {
+	prefix      string
+	replacement string
+}
+
+// WithMirrorRewrite replaces prefix with replacement in the repository of every mirror
+// under prefix once the mirrors of an image are generated, for registries that flatten or
+// relocate the namespaces of the repositories they mirror. For example, the prefix
+// registry.example.com/mirror with the replacement registry.example.com strips the mirror
+// path segment. Only the rule with the most specific prefix applies to a mirror, the
+// requested image is never rewritten, and filters apply to the rewritten mirrors.
+func WithMirrorRewrite(prefix, replacement string) Option {
+	return func(s *OnErrorStrategy) {
+		s.rewrites = append(s.rewrites, mirrorRewrite{prefix: normalizeRepository(prefix), replacement: normalizeRepository(replacement)})
+	}
+}
+
+// rewriteMirrors applies the rewrite rules to the mirrors of r, skipping mirrors that
+// become duplicates of an earlier alternate.
+func (s *OnErrorStrategy) rewriteMirrors(r *resolution) error {
+	if len(s.rewrites) == 0 {
+		return nil
+	}
+	kept := r.alternates[:1]
+	for _, alternate := range r.alternates[1:] {
+		repository := alternate.Ref.AsRepository().Exact()
+		if rule := s.rewriteFor(repository); rule != nil {
+			suffix, _ := matchesSource(repository, rule.prefix)
+			rewritten, err := s.parse(rule.replacement + suffix)
+			if err != nil {
+				return fmt.Errorf("mirror %s rewritten by %s is invalid: %v", repository, rule.prefix, err)
+			}
+			rewritten.Tag, rewritten.ID = alternate.Ref.Tag, alternate.Ref.ID
+			klog.V(5).Infof("Rewrote mirror %s to %s", alternate.Ref.Exact(), rewritten.Exact())
+			alternate.Ref = rewritten
+		}
+		duplicate := false
+		for _, existing := range kept {
+			if existing.Ref == alternate.Ref {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			r.skipped = append(r.skipped, Skipped{Ref: alternate.Ref, Policy: alternate.Policy, Source: alternate.Source, Reason: SkipDuplicate})
+			continue
+		}
+		kept = append(kept, alternate)
+	}
+	r.alternates = kept
+	return nil
+}
+
+// rewriteFor returns the rule with the most specific prefix covering repository, or nil.
+func (s *OnErrorStrategy) rewriteFor(repository string) *mirrorRewrite {
+	var found *mirrorRewrite
+	for i := range s.rewrites {
+		rule := &s.rewrites[i]
+		if _, ok := matchesSource(repository, rule.prefix); !ok {
+			continue
+		}
+		if found == nil || len(rule.prefix) > len(found.prefix) {
+			found = rule
+		}
+	}
+	return found
+}
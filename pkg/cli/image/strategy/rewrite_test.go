@@ -0,0 +1,75 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithMirrorRewrite(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "registry.example.com/mirror/ocp/release", "other.example.com/mirror/ocp/release"),
+			rdm("quay.io/ocp", "registry.example.com/mirror/ocp", "registry.example.com/ocp"),
+		),
+	}}
+	tests := []struct {
+		name     string
+		opts     []Option
+		image    string
+		expected []string
+	}{
+		{
+			name:  "segment stripped",
+			opts:  []Option{WithMirrorRewrite("registry.example.com/mirror", "registry.example.com")},
+			image: "quay.io/ocp/release:4.8",
+			expected: []string{
+				"quay.io/ocp/release:4.8",
+				"registry.example.com/ocp/release:4.8",
+				"other.example.com/mirror/ocp/release:4.8",
+			},
+		},
+		{
+			name:  "most specific rule",
+			opts:  []Option{WithMirrorRewrite("registry.example.com/mirror", "registry.example.com"), WithMirrorRewrite("other.example.com/mirror/ocp", "other.example.com/flat")},
+			image: "quay.io/ocp/release@" + testDigest,
+			expected: []string{
+				"quay.io/ocp/release@" + testDigest,
+				"registry.example.com/ocp/release@" + testDigest,
+				"other.example.com/flat/release@" + testDigest,
+			},
+		},
+		{
+			name:     "duplicates dropped",
+			opts:     []Option{WithMirrorRewrite("registry.example.com/mirror", "registry.example.com")},
+			image:    "quay.io/ocp/installer:4.8",
+			expected: []string{"quay.io/ocp/installer:4.8", "registry.example.com/ocp/installer:4.8"},
+		},
+		{
+			name:     "requested image kept",
+			opts:     []Option{WithMirrorRewrite("quay.io/ocp", "registry.example.com/ocp")},
+			image:    "quay.io/ocp/installer:4.8",
+			expected: []string{"quay.io/ocp/installer:4.8", "registry.example.com/mirror/ocp/installer:4.8", "registry.example.com/ocp/installer:4.8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewICSPOnErrorStrategy(client, "", tt.opts...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	s := NewICSPOnErrorStrategy(client, "", WithMirrorRewrite("registry.example.com/mirror", "Invalid//Registry"))
+	if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8")); err == nil || !strings.Contains(err.Error(), "rewritten by registry.example.com/mirror is invalid") {
+		t.Errorf("expected an invalid rewrite error, got %v", err)
+	}
+}
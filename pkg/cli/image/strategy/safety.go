@@ -0,0 +1,69 @@
+package strategy
+
+import (
+	"fmt"
+)
+
+// DefaultPublicRegistries are the registry domains IsPublicRegistry treats as public.
+var DefaultPublicRegistries = []string{
+	"docker.io",
+	"quay.io",
+	"gcr.io",
+	"ghcr.io",
+	"registry.k8s.io",
+	"k8s.gcr.io",
+	"public.ecr.aws",
+	"mcr.microsoft.com",
+}
+
+// HostClassifier returns true if the registry host is publicly reachable.
+type HostClassifier func(host string) bool
+
+// IsPublicRegistry returns true if host is one of DefaultPublicRegistries or a subdomain
+// of one of them.
+func IsPublicRegistry(host string) bool {
+	host = registryHost(host)
+	for _, domain := range DefaultPublicRegistries {
+		if isSameOrSubdomain(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPublicMirrorCheck flags mirrors on a public registry for images whose source is a
+// private registry, to prevent internal content from being routed through a public host.
+// Hosts are classified by isPublic, or IsPublicRegistry when nil. Flagged mirrors are
+// reported as warnings, or fail the resolution when refuse is set.
+func WithPublicMirrorCheck(isPublic HostClassifier, refuse bool) Option {
+	if isPublic == nil {
+		isPublic = IsPublicRegistry
+	}
+	return func(s *OnErrorStrategy) {
+		s.isPublic = isPublic
+		s.refusePublicMirrors = refuse
+	}
+}
+
+// checkPublicMirrors applies the public mirror check to r, classifying an unqualified
+// requested image by the registry it refers to. The caller must hold s.lock.
+func (s *OnErrorStrategy) checkPublicMirrors(r *resolution) error {
+	if s.isPublic == nil || len(r.alternates) == 0 {
+		return nil
+	}
+	image := r.alternates[0].Ref
+	if s.isPublic(qualifiedRef(image).Registry) {
+		return nil
+	}
+	for _, alternate := range r.alternates[1:] {
+		if !s.isPublic(alternate.Ref.Registry) {
+			continue
+		}
+		message := fmt.Sprintf("private image %s is mirrored to public registry %s by ImageContentSourcePolicy %s", image.Exact(), alternate.Ref.Registry, alternate.Policy)
+		if s.refusePublicMirrors {
+			return fmt.Errorf("refusing to resolve mirrors: %s", message)
+		}
+		s.warn(message)
+	}
+	return nil
+}
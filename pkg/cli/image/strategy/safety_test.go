@@ -0,0 +1,81 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestPublicMirrorCheck(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("internal",
+			rdm("registry.corp.example.com/team/app", "quay.io/someone/app", "mirror.corp.example.com/team/app"),
+			rdm("quay.io/ocp/release", "docker.io/ocp/release"),
+			rdm("docker.io/library/busybox", "quay.io/mirror/busybox"),
+		),
+	}}
+	private := mustParse(t, "registry.corp.example.com/team/app@"+testDigest)
+	public := mustParse(t, "quay.io/ocp/release@"+testDigest)
+
+	t.Run("disabled by default", func(t *testing.T) {
+		warnings := &bytes.Buffer{}
+		s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings))
+		if _, err := s.OnFailure(context.Background(), private); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if warnings.Len() != 0 {
+			t.Errorf("unexpected warnings: %s", warnings.String())
+		}
+	})
+
+	t.Run("warn", func(t *testing.T) {
+		warnings := &bytes.Buffer{}
+		s := NewICSPOnErrorStrategy(client, "", WithWarnings(warnings), WithPublicMirrorCheck(nil, false))
+		alternates, err := s.OnFailure(context.Background(), private)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(alternates) != 3 {
+			t.Errorf("expected the mirrors to still be returned: %v", exactRefs(alternates))
+		}
+		if !strings.Contains(warnings.String(), "private image registry.corp.example.com/team/app@"+testDigest+" is mirrored to public registry quay.io") {
+			t.Errorf("unexpected warnings: %q", warnings.String())
+		}
+		warnings.Reset()
+		if _, err := s.OnFailure(context.Background(), public); err != nil || warnings.Len() != 0 {
+			t.Errorf("expected public sources not to be flagged: %v %s", err, warnings.String())
+		}
+	})
+
+	t.Run("refuse", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(client, "", WithPublicMirrorCheck(nil, true))
+		if _, err := s.OnFailure(context.Background(), private); err == nil || !strings.Contains(err.Error(), "public registry quay.io") {
+			t.Errorf("expected the resolution to be refused, got %v", err)
+		}
+	})
+
+	t.Run("unqualified image", func(t *testing.T) {
+		s := NewICSPOnErrorStrategy(client, "", WithPublicMirrorCheck(nil, true))
+		for _, image := range []string{"busybox:latest", "docker.io/library/busybox:latest"} {
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, image))
+			if err != nil {
+				t.Fatalf("expected %s to be classified as public, got %v", image, err)
+			}
+			if expected := []string{image, "quay.io/mirror/busybox:latest"}; !reflect.DeepEqual(expected, exactRefs(alternates)) {
+				t.Errorf("expected %v, got %v", expected, exactRefs(alternates))
+			}
+		}
+	})
+
+	t.Run("custom classification", func(t *testing.T) {
+		isPublic := func(host string) bool { return host == "mirror.corp.example.com" }
+		s := NewICSPOnErrorStrategy(client, "", WithPublicMirrorCheck(isPublic, true))
+		if _, err := s.OnFailure(context.Background(), private); err == nil || !strings.Contains(err.Error(), "public registry mirror.corp.example.com") {
+			t.Errorf("expected the custom classifier to flag the mirror, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package strategy
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// CopyTool is the command a copy script uses to copy an image.
+type CopyTool string
+
+const (
+	// CopyToolSkopeo copies each image with skopeo copy --all.
+	CopyToolSkopeo CopyTool = "skopeo"
+	// CopyToolOc copies each image with oc image mirror.
+	CopyToolOc CopyTool = "oc"
+)
+
+// WriteCopyScript writes a shell script to w that copies the source of every entry in plan
+// to each of its mirrors with tool, so that the mirrors can be populated offline from the
+// plan alone. Entries without mirrors produce no command.
+func WriteCopyScript(w io.Writer, plan []CopyPlanEntry, tool CopyTool) error {
+	if tool != CopyToolSkopeo && tool != CopyToolOc {
+		return fmt.Errorf("unsupported copy tool %q", tool)
+	}
+	if _, err := fmt.Fprintf(w, "#!/bin/sh\nset -e\n"); err != nil {
+		return err
+	}
+	for _, entry := range plan {
+		for _, target := range entry.Targets {
+			if target == entry.Source {
+				continue
+			}
+			if _, err := fmt.Fprintln(w, copyCommand(tool, entry.Source, target)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copyCommand returns the command that copies source to target with tool.
+func copyCommand(tool CopyTool, source, target reference.DockerImageReference) string {
+	if tool == CopyToolOc {
+		return fmt.Sprintf("oc image mirror %s %s", shellQuote(source.Exact()), shellQuote(target.Exact()))
+	}
+	return fmt.Sprintf("skopeo copy --all %s %s", shellQuote("docker://"+source.Exact()), shellQuote("docker://"+target.Exact()))
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
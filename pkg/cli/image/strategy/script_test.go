@@ -0,0 +1,63 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestWriteCopyScript(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com:5000/ocp/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	plan, err := s.CopyPlan(context.Background(), []reference.DockerImageReference{
+		mustParse(t, "quay.io/ocp/release@"+testDigest),
+		mustParse(t, "quay.io/other/image:latest"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		tool     CopyTool
+		expected string
+	}{
+		{
+			tool: CopyToolSkopeo,
+			expected: "#!/bin/sh\nset -e\n" +
+				"skopeo copy --all 'docker://quay.io/ocp/release@" + testDigest + "' 'docker://registry.example.com/ocp/release@" + testDigest + "'\n" +
+				"skopeo copy --all 'docker://quay.io/ocp/release@" + testDigest + "' 'docker://mirror.example.com:5000/ocp/release@" + testDigest + "'\n",
+		},
+		{
+			tool: CopyToolOc,
+			expected: "#!/bin/sh\nset -e\n" +
+				"oc image mirror 'quay.io/ocp/release@" + testDigest + "' 'registry.example.com/ocp/release@" + testDigest + "'\n" +
+				"oc image mirror 'quay.io/ocp/release@" + testDigest + "' 'mirror.example.com:5000/ocp/release@" + testDigest + "'\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.tool), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			if err := WriteCopyScript(buf, plan, tt.tool); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, buf.String())
+			}
+		})
+	}
+
+	if err := WriteCopyScript(&bytes.Buffer{}, plan, "rsync"); err == nil {
+		t.Errorf("expected an error for an unsupported tool")
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if quoted := shellQuote("it's"); quoted != `'it'\''s'` {
+		t.Errorf("unexpected quoting %s", quoted)
+	}
+}
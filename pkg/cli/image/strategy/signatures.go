@@ -0,0 +1,73 @@
+package strategy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// SignatureStore is the lookaside store holding the signatures of the images under a
+// repository, and the stores mirroring it.
+type SignatureStore struct {
+	// Repository is the registry or repository whose images are signed in the store.
+	Repository string
+	// URL is the base URL of the store.
+	URL string
+	// Mirrors are the base URLs of stores holding copies of the signatures, in the order
+	// they should be tried.
+	Mirrors []string
+}
+
+// SignedAlternates are the locations an image and its signatures may be retrieved from.
+type SignedAlternates struct {
+	// Images are the alternates of the image, as returned by OnFailure.
+	Images []reference.DockerImageReference
+	// SignatureStores are the base URLs of the signature store of the image followed by
+	// its mirrors, or empty if no store is configured for the image.
+	SignatureStores []string
+}
+
+// WithSignatureStore declares the signature store of the images under store.Repository,
+// so that ResolveWithSignatures offers the mirrors of the store along with those of the
+// image. The store with the most specific repository covering an image applies.
+func WithSignatureStore(store SignatureStore) Option {
+	return func(s *OnErrorStrategy) {
+		store.Repository = normalizeRepository(store.Repository)
+		s.signatureStores = append(s.signatureStores, store)
+	}
+}
+
+// ResolveWithSignatures returns the alternates of locator together with the signature
+// stores its signatures may be retrieved from.
+func (s *OnErrorStrategy) ResolveWithSignatures(ctx context.Context, locator reference.DockerImageReference) (*SignedAlternates, error) {
+	images, err := s.OnFailure(ctx, locator)
+	if err != nil {
+		return nil, err
+	}
+	signed := &SignedAlternates{Images: images}
+	if store := s.signatureStoreFor(locator); store != nil {
+		signed.SignatureStores = append(signed.SignatureStores, strings.TrimRight(store.URL, "/"))
+		for _, mirror := range store.Mirrors {
+			signed.SignatureStores = append(signed.SignatureStores, strings.TrimRight(mirror, "/"))
+		}
+	}
+	return signed, nil
+}
+
+// signatureStoreFor returns the store with the most specific repository covering
+// locator, or nil. An unqualified locator is matched as the repository it refers to.
+func (s *OnErrorStrategy) signatureStoreFor(locator reference.DockerImageReference) *SignatureStore {
+	repository := qualifiedRef(locator).AsRepository().Exact()
+	var found *SignatureStore
+	for i := range s.signatureStores {
+		store := &s.signatureStores[i]
+		if _, ok := matchesSource(repository, store.Repository); !ok {
+			continue
+		}
+		if found == nil || len(store.Repository) > len(found.Repository) {
+			found = store
+		}
+	}
+	return found
+}
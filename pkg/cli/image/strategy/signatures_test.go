@@ -0,0 +1,69 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestResolveWithSignatures(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/openshift-release-dev/ocp-release", "registry.example.com/ocp/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "",
+		WithSignatureStore(SignatureStore{
+			Repository: "quay.io",
+			URL:        "https://signatures.example.com/quay",
+		}),
+		WithSignatureStore(SignatureStore{
+			Repository: "quay.io/openshift-release-dev/",
+			URL:        "https://mirror.openshift.com/pub/openshift-v4/signatures/openshift/release/",
+			Mirrors:    []string{"https://signatures.example.com/ocp/release"},
+		}),
+		WithSignatureStore(SignatureStore{
+			Repository: "docker.io/ocp/release",
+			URL:        "https://signatures.example.com/hub",
+		}),
+	)
+	tests := []struct {
+		image          string
+		expectedImages []string
+		expectedStores []string
+	}{
+		{
+			image:          "quay.io/openshift-release-dev/ocp-release@" + testDigest,
+			expectedImages: []string{"quay.io/openshift-release-dev/ocp-release@" + testDigest, "registry.example.com/ocp/release@" + testDigest},
+			expectedStores: []string{"https://mirror.openshift.com/pub/openshift-v4/signatures/openshift/release", "https://signatures.example.com/ocp/release"},
+		},
+		{
+			image:          "quay.io/other/image@" + testDigest,
+			expectedImages: []string{"quay.io/other/image@" + testDigest},
+			expectedStores: []string{"https://signatures.example.com/quay"},
+		},
+		{
+			image:          "ocp/release@" + testDigest,
+			expectedImages: []string{"ocp/release@" + testDigest},
+			expectedStores: []string{"https://signatures.example.com/hub"},
+		},
+		{
+			image:          "docker.io/library/busybox@" + testDigest,
+			expectedImages: []string{"docker.io/library/busybox@" + testDigest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			signed, err := s.ResolveWithSignatures(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(signed.Images); !reflect.DeepEqual(tt.expectedImages, actual) {
+				t.Errorf("expected images %v, got %v", tt.expectedImages, actual)
+			}
+			if !reflect.DeepEqual(tt.expectedStores, signed.SignatureStores) {
+				t.Errorf("expected signature stores %v, got %v", tt.expectedStores, signed.SignatureStores)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package strategy
+
+import "context"
+
+// Snapshot returns a strategy configured like s that resolves against the policies as
+// they are loaded now, so that a batch job is unaffected by later changes to the cluster
+// or to policy files. The snapshot starts with empty caches and statistics, and Init does
+// not reload its policies.
+func (s *OnErrorStrategy) Snapshot(ctx context.Context) (*OnErrorStrategy, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	icspList, err := s.loadICSPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := s.indexFor(icspList)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := NewICSPOnErrorStrategy(s.icspClient, s.icspFile, s.opts...)
+	if err := s.loadOverrides(); err != nil {
+		return nil, err
+	}
+	snapshot.overrides = s.overrides
+	// The insecure mirrors of registries.conf files are only recorded as they are loaded,
+	// which the snapshot never does.
+	for path, hosts := range s.insecureConf {
+		snapshot.insecureConf[path] = hosts
+	}
+	snapshot.preloaded = icspList
+	snapshot.preloadedIndex = idx
+	snapshot.initialized = true
+	snapshot.snapshot = true
+	return snapshot, nil
+}
@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestSnapshot(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "registry.example.com/ocp/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "", WithHomeRegistry("home.example.com"))
+	snapshot, err := s.Snapshot(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	client.items = []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "changed.example.com/ocp/release", "home.example.com/ocp/release")),
+	}
+	calls := client.calls
+	image := mustParse(t, "quay.io/ocp/release:4.8")
+	for i := 0; i < 2; i++ {
+		if err := snapshot.Init(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		alternates, err := snapshot.OnFailure(context.Background(), image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{"quay.io/ocp/release:4.8", "registry.example.com/ocp/release:4.8"}
+		if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected the snapshot to be unchanged %v, got %v", expected, got)
+		}
+	}
+	if client.calls != calls {
+		t.Errorf("expected the snapshot not to list policies again, got %d more calls", client.calls-calls)
+	}
+
+	alternates, err := s.OnFailure(context.Background(), image)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"quay.io/ocp/release:4.8", "home.example.com/ocp/release:4.8", "changed.example.com/ocp/release:4.8"}
+	if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected the original strategy to see the change and keep its options %v, got %v", expected, got)
+	}
+}
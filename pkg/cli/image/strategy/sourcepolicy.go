@@ -0,0 +1,66 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/klog/v2"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+const (
+	// NeverContactSource is the mirrorSourcePolicy of an ImageDigestMirrorSet or
+	// ImageTagMirrorSet entry whose source must only be retrieved from its mirrors.
+	NeverContactSource = "NeverContactSource"
+
+	// NeverContactSourceAnnotation lists the sources of a policy that must only be retrieved
+	// from their mirrors, since an ImageContentSourcePolicy cannot declare a mirrorSourcePolicy.
+	// The value is a JSON array of sources, e.g. ["quay.io/ocp/release"]. Mirror sets are
+	// converted with this annotation for their NeverContactSource entries.
+	NeverContactSourceAnnotation = "mirror.openshift.io/never-contact-source"
+)
+
+// WithSourceRetained returns the requested image after its mirrors, as a last resort, even
+// when a matching source is declared NeverContactSource, for break-glass runs where the
+// mirrors are known to be out of date or unavailable. It has no effect on images whose
+// sources may be contacted, or on requested images omitted for another reason, such as
+// being on a blocked registry.
+func WithSourceRetained() Option {
+	return func(s *OnErrorStrategy) {
+		s.retainSource = true
+	}
+}
+
+// neverContactSources returns the normalized sources icsp declares NeverContactSource.
+func neverContactSources(icsp *operatorv1alpha1.ImageContentSourcePolicy) (map[string]bool, error) {
+	value, ok := icsp.Annotations[NeverContactSourceAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var declared []string
+	if err := json.Unmarshal([]byte(value), &declared); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation on ImageContentSourcePolicy %s: %v", NeverContactSourceAnnotation, icsp.Name, err)
+	}
+	sources := make(map[string]bool, len(declared))
+	for _, source := range declared {
+		sources[normalizeRepository(source)] = true
+	}
+	return sources, nil
+}
+
+// avoidSource omits the requested image from r when a matching source is declared
+// NeverContactSource, or moves it after the mirrors if WithSourceRetained was set.
+func (s *OnErrorStrategy) avoidSource(r *resolution) {
+	if !r.neverContactSource || r.sourceOmitted {
+		return
+	}
+	image := r.alternates[0].Ref
+	if s.retainSource {
+		klog.V(4).Infof("Retaining %s after its mirrors although its source is declared %s", image.Exact(), NeverContactSource)
+		r.sourceLast = true
+		return
+	}
+	r.sourceOmitted = true
+	r.skipped = append(r.skipped, Skipped{Ref: image, Reason: SkipNeverContactSource})
+}
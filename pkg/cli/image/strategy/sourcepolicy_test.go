@@ -0,0 +1,107 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+const neverContactPolicy = `apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: release
+  annotations:
+    example.com/owner: release-team
+spec:
+  imageDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - first.example.com/ocp/release
+    - second.example.com/ocp/release
+    mirrorSourcePolicy: NeverContactSource
+  - source: quay.io/ocp/tools
+    mirrors:
+    - first.example.com/ocp/tools
+    mirrorSourcePolicy: AllowContactingSource
+`
+
+func TestNeverContactSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		image    string
+		expected []string
+	}{
+		{
+			name:  "source omitted",
+			image: "quay.io/ocp/release:4.8",
+			expected: []string{
+				"first.example.com/ocp/release:4.8",
+				"second.example.com/ocp/release:4.8",
+			},
+		},
+		{
+			name:  "source retained",
+			opts:  []Option{WithSourceRetained()},
+			image: "quay.io/ocp/release:4.8",
+			expected: []string{
+				"first.example.com/ocp/release:4.8",
+				"second.example.com/ocp/release:4.8",
+				"quay.io/ocp/release:4.8",
+			},
+		},
+		{
+			name:     "source allowed",
+			image:    "quay.io/ocp/tools:4.8",
+			expected: []string{"quay.io/ocp/tools:4.8", "first.example.com/ocp/tools:4.8"},
+		},
+		{
+			name:     "source allowed and retained",
+			opts:     []Option{WithSourceRetained()},
+			image:    "quay.io/ocp/tools:4.8",
+			expected: []string{"quay.io/ocp/tools:4.8", "first.example.com/ocp/tools:4.8"},
+		},
+		{
+			name:     "blocked source not retained",
+			opts:     []Option{WithSourceRetained(), WithBlockedRegistries("quay.io")},
+			image:    "quay.io/ocp/release:4.8",
+			expected: []string{"first.example.com/ocp/release:4.8", "second.example.com/ocp/release:4.8"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithInlinePolicy(neverContactPolicy)}, tt.opts...)
+			s := NewICSPOnErrorStrategy(nil, "", opts...)
+			alternates, err := s.OnFailure(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := exactRefs(alternates); !reflect.DeepEqual(tt.expected, actual) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestNeverContactSourceAnnotation(t *testing.T) {
+	icsp := newICSP("release", rdm("quay.io/ocp/release", "first.example.com/ocp/release"))
+	icsp.Annotations = map[string]string{NeverContactSourceAnnotation: `["quay.io/ocp/release/"]`}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{icsp}}
+	s := NewICSPOnErrorStrategy(client, "", WithSourceRetained())
+	alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release@"+testDigest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"first.example.com/ocp/release@" + testDigest, "quay.io/ocp/release@" + testDigest}
+	if actual := exactRefs(alternates); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	icsp.Annotations[NeverContactSourceAnnotation] = "quay.io/ocp/release"
+	invalid := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{icsp}}
+	if _, err := NewICSPOnErrorStrategy(invalid, "").OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release@"+testDigest)); err == nil {
+		t.Errorf("expected an error for an invalid annotation")
+	}
+}
@@ -0,0 +1,52 @@
+package strategy
+
+import (
+	"sort"
+
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// WithAdaptiveOrdering orders the mirrors returned by OnFailure by the number of
+// successes recorded for their registry, most successful first, so that a long run
+// converges on the mirrors that work. Ties keep policy order.
+func WithAdaptiveOrdering() Option {
+	return func(s *OnErrorStrategy) {
+		s.adaptive = true
+	}
+}
+
+// RecordSuccess reports that imageRef was ultimately retrieved from mirrorRef, which is
+// one of the alternates of imageRef.
+func (s *OnErrorStrategy) RecordSuccess(imageRef, mirrorRef reference.DockerImageReference) {
+	klog.V(2).Infof("Retrieved %s from %s", imageRef.Exact(), mirrorRef.Exact())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.successes[mirrorRef.Registry]++
+}
+
+// SuccessStats returns the number of successes recorded for each mirror registry.
+func (s *OnErrorStrategy) SuccessStats() map[string]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stats := make(map[string]int, len(s.successes))
+	for registry, count := range s.successes {
+		stats[registry] = count
+	}
+	return stats
+}
+
+// orderBySuccess returns a copy of alternates with the mirrors ordered by recorded
+// successes. The requested image stays first. The caller must hold s.lock.
+func (s *OnErrorStrategy) orderBySuccess(alternates []Alternate) []Alternate {
+	ordered := append([]Alternate(nil), alternates...)
+	if len(ordered) < 3 {
+		return ordered
+	}
+	mirrors := ordered[1:]
+	sort.SliceStable(mirrors, func(i, j int) bool {
+		return s.successes[mirrors[i].Ref.Registry] > s.successes[mirrors[j].Ref.Registry]
+	})
+	return ordered
+}
@@ -0,0 +1,46 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRecordSuccess(t *testing.T) {
+	image := mustParse(t, "quay.io/ocp-test/release@"+testDigest)
+	other := mustParse(t, "quay.io/ocp-test/other@"+testDigest)
+	for _, adaptive := range []bool{false, true} {
+		var opts []Option
+		if adaptive {
+			opts = append(opts, WithAdaptiveOrdering())
+		}
+		s := NewICSPOnErrorStrategy(nil, "testdata/icsp.yaml", opts...)
+		if _, err := s.OnFailure(context.Background(), image); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s.RecordSuccess(image, mustParse(t, "mirror.example.com/ocp-test/release@"+testDigest))
+		s.RecordSuccess(other, mustParse(t, "mirror.example.com/ocp-test/other@"+testDigest))
+		s.RecordSuccess(other, mustParse(t, "registry.example.com/ocp-test/other@"+testDigest))
+
+		expectedStats := map[string]int{"mirror.example.com": 2, "registry.example.com": 1}
+		if stats := s.SuccessStats(); !reflect.DeepEqual(stats, expectedStats) {
+			t.Errorf("expected stats %v, got %v", expectedStats, stats)
+		}
+
+		alternates, err := s.OnFailure(context.Background(), image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := []string{
+			"quay.io/ocp-test/release@" + testDigest,
+			"registry.example.com/ocp-test/release@" + testDigest,
+			"mirror.example.com/ocp-test/release@" + testDigest,
+		}
+		if adaptive {
+			expected[1], expected[2] = expected[2], expected[1]
+		}
+		if got := exactRefs(alternates); !reflect.DeepEqual(got, expected) {
+			t.Errorf("adaptive=%t: expected %v, got %v", adaptive, expected, got)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package strategytest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/oc/pkg/cli/image/strategy"
+)
+
+// AssertResolves fails t unless s resolves image to exactly the expected references, in
+// the order returned by OnFailure. expected lists the requested image too wherever
+// OnFailure returns it, which may be last or nowhere depending on the options of s. An
+// image that cannot be parsed or resolved stops the test.
+func AssertResolves(t testing.TB, s *strategy.OnErrorStrategy, image string, expected []string) {
+	t.Helper()
+	ref, err := strategy.ParseReference(image)
+	if err != nil {
+		t.Fatalf("unable to parse %q: %v", image, err)
+	}
+	alternates, err := s.OnFailure(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("unable to resolve %s: %v", image, err)
+	}
+	got := make([]string, 0, len(alternates))
+	for _, alternate := range alternates {
+		got = append(got, alternate.Exact())
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("%s: expected %v, got %v", image, expected, got)
+	}
+}
@@ -0,0 +1,122 @@
+package strategytest
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/openshift/oc/pkg/cli/image/strategy"
+)
+
+const testDigest = "sha256:a2c3a44b9fb636c2c1e6c5e2b4ad3e91cf7a2f1b81d4b48dc82b1a6c6f0e8f3d"
+
+const policy = `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: release
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - registry.example.com/ocp/release
+`
+
+// recordingT records the failures reported to it instead of failing the test.
+type recordingT struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *recordingT) Fatalf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+	t.fatal = true
+	runtime.Goexit()
+}
+
+// run calls fn with a recordingT on its own goroutine so that Fatalf can stop it.
+func run(fn func(t testing.TB)) *recordingT {
+	t := &recordingT{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		fn(t)
+	}()
+	wg.Wait()
+	return t
+}
+
+func TestAssertResolves(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []strategy.Option
+		image    string
+		expected []string
+		errors   []string
+		fatal    bool
+	}{
+		{
+			name:     "matching",
+			image:    "quay.io/ocp/release@" + testDigest,
+			expected: []string{"quay.io/ocp/release@" + testDigest, "registry.example.com/ocp/release@" + testDigest},
+		},
+		{
+			name:     "unmirrored",
+			image:    "quay.io/other/app:1.0",
+			expected: []string{"quay.io/other/app:1.0"},
+		},
+		{
+			name:     "mismatch",
+			image:    "quay.io/ocp/release:4.8",
+			expected: []string{"quay.io/ocp/release:4.8"},
+			errors:   []string{"quay.io/ocp/release:4.8: expected [quay.io/ocp/release:4.8], got [quay.io/ocp/release:4.8 registry.example.com/ocp/release:4.8]"},
+		},
+		{
+			name:     "source omitted",
+			opts:     []strategy.Option{strategy.WithBlockedRegistries("quay.io")},
+			image:    "quay.io/ocp/release@" + testDigest,
+			expected: []string{"registry.example.com/ocp/release@" + testDigest},
+		},
+		{
+			name:     "IPv6 registry",
+			image:    "[fd00::1]:5000/ocp/release:4.8",
+			expected: []string{"[fd00::1]:5000/ocp/release:4.8"},
+		},
+		{
+			name:   "invalid image",
+			image:  "Invalid//Image",
+			errors: []string{`unable to parse "Invalid//Image": invalid reference format`},
+			fatal:  true,
+		},
+		{
+			name:   "resolution error",
+			opts:   []strategy.Option{strategy.WithRequiredDigest()},
+			image:  "quay.io/ocp/release:4.8",
+			errors: []string{`unable to resolve quay.io/ocp/release:4.8: image "quay.io/ocp/release:4.8" is not pinned to a digest, which is required`},
+			fatal:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]strategy.Option{strategy.WithInlinePolicy(policy)}, tt.opts...)
+			s := strategy.NewICSPOnErrorStrategy(nil, "", opts...)
+			recorded := run(func(t testing.TB) {
+				AssertResolves(t, s, tt.image, tt.expected)
+			})
+			if fmt.Sprint(recorded.errors) != fmt.Sprint(tt.errors) {
+				t.Errorf("expected failures %q, got %q", tt.errors, recorded.errors)
+			}
+			if recorded.fatal != tt.fatal {
+				t.Errorf("expected fatal %t, got %t", tt.fatal, recorded.fatal)
+			}
+		})
+	}
+}
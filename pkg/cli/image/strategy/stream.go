@@ -0,0 +1,74 @@
+package strategy
+
+import (
+	"context"
+	"sync"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ResolveResult is the outcome of resolving one image of a batch.
+type ResolveResult struct {
+	Image reference.DockerImageReference
+	// Alternates are the alternates of Image as returned by OnFailure, or nil if Err is set.
+	Alternates []reference.DockerImageReference
+	Err        error
+}
+
+// ResolveStream resolves images with at most concurrency lookups in flight and sends the
+// result of each image on the returned channel as soon as it is known, in no particular
+// order, closing the channel once every image has been sent. Unless Init was called the
+// policies are loaded once for the whole batch, as by Snapshot, so that very large batches
+// neither list the policies per image nor hold every result in memory. Images still queued
+// when ctx is cancelled are sent with its error. Values of concurrency below one resolve
+// one image at a time. The caller must receive every result.
+func (s *OnErrorStrategy) ResolveStream(ctx context.Context, images []reference.DockerImageReference, concurrency int) <-chan ResolveResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make(chan ResolveResult, concurrency)
+	go func() {
+		defer close(results)
+
+		resolver, err := s.batchResolver(ctx)
+		if err != nil {
+			for _, image := range images {
+				results <- ResolveResult{Image: image, Err: err}
+			}
+			return
+		}
+		queue := make(chan reference.DockerImageReference)
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for image := range queue {
+					result := ResolveResult{Image: image}
+					if result.Err = ctx.Err(); result.Err == nil {
+						result.Alternates, result.Err = resolver.OnFailure(ctx, image)
+					}
+					results <- result
+				}
+			}()
+		}
+		for _, image := range images {
+			queue <- image
+		}
+		close(queue)
+		wg.Wait()
+	}()
+	return results
+}
+
+// batchResolver returns the strategy resolving a batch: s itself if Init loaded its
+// policies, or else a snapshot of them.
+func (s *OnErrorStrategy) batchResolver(ctx context.Context) (*OnErrorStrategy, error) {
+	s.lock.Lock()
+	initialized := s.initialized
+	s.lock.Unlock()
+	if initialized {
+		return s, nil
+	}
+	return s.Snapshot(ctx)
+}
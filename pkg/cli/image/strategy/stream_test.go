@@ -0,0 +1,143 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+func TestResolveStream(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp", "registry.example.com/ocp")),
+	}}
+	var lock sync.Mutex
+	var inFlight, peak int
+	resolver := DigestResolverFunc(func(ctx context.Context, ref reference.DockerImageReference) (digest.Digest, error) {
+		lock.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		lock.Unlock()
+		time.Sleep(time.Millisecond)
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return digest.Digest(testDigest), nil
+	})
+	s := NewICSPOnErrorStrategy(client, "", WithDigestPinning(resolver))
+
+	var images []reference.DockerImageReference
+	for i := 0; i < 500; i++ {
+		images = append(images, mustParse(t, fmt.Sprintf("quay.io/ocp/image%d:latest", i)))
+	}
+	emitted := make(map[string]int)
+	for result := range s.ResolveStream(context.Background(), images, 4) {
+		if result.Err != nil {
+			t.Fatalf("unexpected error for %s: %v", result.Image.Exact(), result.Err)
+		}
+		if len(result.Alternates) != 2 || result.Alternates[1].Registry != "registry.example.com" {
+			t.Errorf("unexpected alternates for %s: %v", result.Image.Exact(), exactRefs(result.Alternates))
+		}
+		emitted[result.Image.Exact()]++
+	}
+	if len(emitted) != len(images) {
+		t.Errorf("expected %d images to be emitted, got %d", len(images), len(emitted))
+	}
+	for image, count := range emitted {
+		if count != 1 {
+			t.Errorf("expected %s to be emitted once, got %d", image, count)
+		}
+	}
+	if peak > 4 {
+		t.Errorf("expected at most 4 lookups in flight, got %d", peak)
+	}
+	if client.calls != 1 {
+		t.Errorf("expected the policies to be listed once, got %d", client.calls)
+	}
+}
+
+func TestResolveStreamProbesOverlap(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp", "registry.example.com/ocp")),
+	}}
+	var lock sync.Mutex
+	var inFlight, peak int
+	overlapping := make(chan struct{})
+	var closeOnce sync.Once
+	prober := ProberFunc(func(ctx context.Context, ref reference.DockerImageReference) error {
+		lock.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		if inFlight == 2 {
+			closeOnce.Do(func() { close(overlapping) })
+		}
+		lock.Unlock()
+		// Wait for another probe to start, which never happens if probes are serialized.
+		select {
+		case <-overlapping:
+		case <-time.After(time.Second):
+		}
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+		return nil
+	})
+	s := NewICSPOnErrorStrategy(client, "", WithProbe(prober))
+
+	var images []reference.DockerImageReference
+	for i := 0; i < 8; i++ {
+		images = append(images, mustParse(t, fmt.Sprintf("quay.io/ocp/image%d:latest", i)))
+	}
+	for result := range s.ResolveStream(context.Background(), images, 8) {
+		if result.Err != nil {
+			t.Fatalf("unexpected error for %s: %v", result.Image.Exact(), result.Err)
+		}
+	}
+	if peak < 2 {
+		t.Errorf("expected the mirrors of several images to be probed at once, at most %d were", peak)
+	}
+}
+
+func TestResolveStreamErrors(t *testing.T) {
+	images := []reference.DockerImageReference{mustParse(t, "quay.io/ocp/a:latest"), mustParse(t, "quay.io/ocp/b:latest")}
+	tests := []struct {
+		name     string
+		client   *fakeICSPLister
+		cancel   bool
+		expected string
+	}{
+		{name: "policies", client: &fakeICSPLister{err: fmt.Errorf("forbidden")}, expected: "forbidden"},
+		{name: "cancelled", client: &fakeICSPLister{}, cancel: true, expected: context.Canceled.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			s := NewICSPOnErrorStrategy(tt.client, "")
+			count := 0
+			for result := range s.ResolveStream(ctx, images, 0) {
+				count++
+				if result.Err == nil || !strings.Contains(result.Err.Error(), tt.expected) {
+					t.Errorf("expected an error containing %q for %s, got %v", tt.expected, result.Image.Exact(), result.Err)
+				}
+			}
+			if count != len(images) {
+				t.Errorf("expected %d results, got %d", len(images), count)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package strategy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+)
+
+// CertificateFetcher returns the certificate chain presented by the server at addr, leaf
+// first, without verifying it.
+type CertificateFetcher func(ctx context.Context, addr string) ([]*x509.Certificate, error)
+
+// TLSResult is the outcome of checking the certificate of one mirror registry.
+type TLSResult struct {
+	Registry string
+	// Error describes why the certificate could not be retrieved or is not trusted, or is
+	// empty if it is valid.
+	Error string
+}
+
+// DefaultCertificateFetcher retrieves certificates with a TLS handshake against addr.
+func DefaultCertificateFetcher(ctx context.Context, addr string) ([]*x509.Certificate, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: host, InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().PeerCertificates, nil
+}
+
+// CheckMirrorTLS verifies that every mirror registry of the effective policy presents a
+// certificate issued for its host by one of the certificate authorities in the PEM encoded
+// caBundle, so that an untrusted mirror is reported before any image is copied. Registries
+// are checked once each, in alphabetical order, with fetch or DefaultCertificateFetcher
+// when nil.
+func (s *OnErrorStrategy) CheckMirrorTLS(ctx context.Context, caBundle []byte, fetch CertificateFetcher) ([]TLSResult, error) {
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caBundle) {
+		return nil, fmt.Errorf("the CA bundle contains no certificates")
+	}
+	if fetch == nil {
+		fetch = DefaultCertificateFetcher
+	}
+	policy, err := s.EffectivePolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var registries []string
+	for _, source := range policy.Sources {
+		for _, mirror := range source.Mirrors {
+			ref, err := s.parse(mirror)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mirror %q for source %q: %v", mirror, source.Source, err)
+			}
+			registry := ref.DockerClientDefaults().Registry
+			if !seen[registry] {
+				seen[registry] = true
+				registries = append(registries, registry)
+			}
+		}
+	}
+	sort.Strings(registries)
+	var results []TLSResult
+	for _, registry := range registries {
+		result := TLSResult{Registry: registry}
+		if err := verifyRegistryTLS(ctx, registry, roots, fetch); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// verifyRegistryTLS checks the certificate presented by registry against roots.
+func verifyRegistryTLS(ctx context.Context, registry string, roots *x509.CertPool, fetch CertificateFetcher) error {
+	addr := registry
+	if _, _, err := net.SplitHostPort(registry); err != nil {
+		addr = net.JoinHostPort(registryHost(registry), "443")
+	}
+	certs, err := fetch(ctx, addr)
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the certificate of %s: %v", addr, err)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf("%s presented no certificate", addr)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = certs[0].Verify(x509.VerifyOptions{
+		DNSName:       registryHost(registry),
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
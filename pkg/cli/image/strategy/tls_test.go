@@ -0,0 +1,135 @@
+package strategy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) pem() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})
+}
+
+func (ca *testCA) issue(t *testing.T, host string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func TestCheckMirrorTLS(t *testing.T) {
+	trusted := newTestCA(t, "trusted")
+	untrusted := newTestCA(t, "untrusted")
+	certs := map[string][]*x509.Certificate{
+		"registry.example.com:443": {trusted.issue(t, "registry.example.com")},
+		"mirror.example.com:5000":  {untrusted.issue(t, "mirror.example.com")},
+		"other.example.com:443":    {trusted.issue(t, "wrong.example.com")},
+	}
+	var fetched []string
+	fetch := func(ctx context.Context, addr string) ([]*x509.Certificate, error) {
+		fetched = append(fetched, addr)
+		chain, ok := certs[addr]
+		if !ok {
+			return nil, fmt.Errorf("connection refused")
+		}
+		return chain, nil
+	}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release",
+			rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "mirror.example.com:5000/ocp/release"),
+			rdm("quay.io/ocp/installer", "registry.example.com/ocp/installer", "other.example.com/ocp/installer", "down.example.com/ocp/installer"),
+		),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	results, err := s.CheckMirrorTLS(context.Background(), trusted.pem(), fetch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var registries []string
+	for _, result := range results {
+		registries = append(registries, result.Registry)
+	}
+	expected := []string{"down.example.com", "mirror.example.com:5000", "other.example.com", "registry.example.com"}
+	if !reflect.DeepEqual(expected, registries) {
+		t.Fatalf("expected results for %v, got %v", expected, registries)
+	}
+	expectedErrors := []string{"connection refused", "certificate signed by unknown authority", "valid for wrong.example.com", ""}
+	for i, result := range results {
+		if len(expectedErrors[i]) == 0 {
+			if len(result.Error) > 0 {
+				t.Errorf("%s: unexpected error %s", result.Registry, result.Error)
+			}
+			continue
+		}
+		if !strings.Contains(result.Error, expectedErrors[i]) {
+			t.Errorf("%s: expected an error containing %q, got %q", result.Registry, expectedErrors[i], result.Error)
+		}
+	}
+	if len(fetched) != 4 {
+		t.Errorf("expected each registry to be checked once, got %v", fetched)
+	}
+
+	if _, err := s.CheckMirrorTLS(context.Background(), []byte("not a bundle"), fetch); err == nil {
+		t.Errorf("expected an error for an empty CA bundle")
+	}
+}
@@ -0,0 +1,77 @@
+package strategy
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+// ValidationError is a policy that would fail the resolution of a matching image, with the
+// path of the offending field so that it can be found in the file it was read from.
+type ValidationError struct {
+	// File is the policy file, or empty for a policy that was not read from a file.
+	File string
+	// Document is the index of the document in File, or -1 if unknown.
+	Document int
+	// Policy is the name of the invalid policy.
+	Policy string
+	// Field is the path of the offending field, such as spec.repositoryDigestMirrors[2].mirrors[0].
+	Field string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	location := e.Field
+	if e.Document >= 0 {
+		location = fmt.Sprintf("document %d: %s", e.Document, location)
+	}
+	if len(e.File) > 0 {
+		location = fmt.Sprintf("ImageContentSourcePolicy file %s, %s", e.File, location)
+	}
+	return fmt.Sprintf("%s: %v", location, e.Err)
+}
+
+// validatePolicy returns an error for the first mirror or annotation of icsp that would
+// fail the resolution of a matching image. The document of the error is unknown.
+func validatePolicy(icsp *operatorv1alpha1.ImageContentSourcePolicy, parse ReferenceParser) *ValidationError {
+	invalid := func(path *field.Path, err error) *ValidationError {
+		return &ValidationError{Document: -1, Policy: icsp.Name, Field: path.String(), Err: err}
+	}
+	annotations := field.NewPath("metadata", "annotations")
+	if _, err := tagPatterns(icsp); err != nil {
+		return invalid(annotations.Key(TagPatternsAnnotation), err)
+	}
+	if _, err := redirects(icsp); err != nil {
+		return invalid(annotations.Key(RedirectsAnnotation), err)
+	}
+	if _, err := neverContactSources(icsp); err != nil {
+		return invalid(annotations.Key(NeverContactSourceAnnotation), err)
+	}
+	entries := entriesPath(icsp.Kind)
+	for i, rdm := range icsp.Spec.RepositoryDigestMirrors {
+		if isExcluded(rdm) {
+			continue
+		}
+		for j, mirror := range rdm.Mirrors {
+			if _, err := parse(normalizeRepository(mirror)); err != nil {
+				return invalid(entries.Index(i).Child("mirrors").Index(j), fmt.Errorf("invalid mirror %q for source %q in ImageContentSourcePolicy %s: %v", mirror, rdm.Source, icsp.Name, err))
+			}
+		}
+	}
+	return nil
+}
+
+// entriesPath returns the path of the source entries in a policy of kind, as declared
+// before mirror sets were converted.
+func entriesPath(kind string) *field.Path {
+	switch kind {
+	case "ImageDigestMirrorSet":
+		return field.NewPath("spec", "imageDigestMirrors")
+	case "ImageTagMirrorSet":
+		return field.NewPath("spec", "imageTagMirrors")
+	default:
+		return field.NewPath("spec", "repositoryDigestMirrors")
+	}
+}
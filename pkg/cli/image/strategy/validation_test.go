@@ -0,0 +1,133 @@
+package strategy
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+const malformedPolicies = `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: valid
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/tools
+    mirrors:
+    - registry.example.com/ocp/tools
+---
+apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: malformed
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - registry.example.com/ocp/release
+  - source: quay.io/ocp/installer
+    mirrors:
+    - "-"
+  - source: quay.io/ocp/cli
+    mirrors:
+    - Invalid//Mirror
+`
+
+const malformedMirrorSet = `apiVersion: config.openshift.io/v1
+kind: ImageDigestMirrorSet
+metadata:
+  name: malformed
+spec:
+  imageDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - registry.example.com/ocp/release
+    - Invalid//Mirror
+`
+
+const malformedAnnotation = `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: malformed
+  annotations:
+    mirror.openshift.io/tag-patterns: "4.*"
+spec:
+  repositoryDigestMirrors:
+  - source: quay.io/ocp/release
+    mirrors:
+    - registry.example.com/ocp/release
+`
+
+func TestValidationErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "policy-validation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		policies string
+		document int
+		field    string
+	}{
+		{
+			name:     "mirror",
+			policies: malformedPolicies,
+			document: 1,
+			field:    "spec.repositoryDigestMirrors[2].mirrors[0]",
+		},
+		{
+			name:     "mirror set",
+			policies: malformedMirrorSet,
+			document: 0,
+			field:    "spec.imageDigestMirrors[0].mirrors[1]",
+		},
+		{
+			name:     "annotation",
+			policies: malformedAnnotation,
+			document: 0,
+			field:    "metadata.annotations[mirror.openshift.io/tag-patterns]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".yaml")
+			if err := ioutil.WriteFile(path, []byte(tt.policies), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := NewICSPOnErrorStrategy(nil, path).Init(context.Background())
+			invalid, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+			if invalid.File != path || invalid.Document != tt.document || invalid.Field != tt.field || invalid.Policy != "malformed" {
+				t.Errorf("expected %s, document %d, field %s of policy malformed, got %#v", path, tt.document, tt.field, invalid)
+			}
+			if !strings.Contains(err.Error(), tt.field) {
+				t.Errorf("expected the error to contain the field path %s, got %v", tt.field, err)
+			}
+		})
+	}
+
+	t.Run("listed policy", func(t *testing.T) {
+		client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+			newICSP("valid", rdm("quay.io/ocp/tools", "registry.example.com/ocp/tools")),
+			newICSP("broken", rdm("quay.io/ocp/release", "registry.example.com/ocp/release", "Invalid//Mirror")),
+		}}
+		err := NewICSPOnErrorStrategy(client, "").Init(context.Background())
+		invalid, ok := err.(*ValidationError)
+		if !ok {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+		expected := `spec.repositoryDigestMirrors[0].mirrors[1]: invalid mirror "Invalid//Mirror" for source "quay.io/ocp/release" in ImageContentSourcePolicy broken: invalid reference format`
+		if invalid.Document != -1 || err.Error() != expected {
+			t.Errorf("expected %q without a document, got %q in document %d", expected, err.Error(), invalid.Document)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package strategy
+
+import (
+	"strings"
+
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// markRegistryMirrors sets the registry-only variant of each mirror of r that a
+// registry-level entry declared, which is the case when the source of the mirror is the
+// registry of image and the mirror keeps the repository path of image. image must be
+// qualified.
+func (r *resolution) markRegistryMirrors(image reference.DockerImageReference) {
+	for i := 1; i < len(r.alternates); i++ {
+		alternate := &r.alternates[i]
+		if len(alternate.Policy) == 0 || !strings.EqualFold(alternate.Source, image.Registry) {
+			continue
+		}
+		if alternate.Ref.RepositoryName() == image.RepositoryName() {
+			alternate.RegistryRef = reference.DockerImageReference{Registry: alternate.Ref.Registry}
+		}
+	}
+}
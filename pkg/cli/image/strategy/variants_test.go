@@ -0,0 +1,61 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestRegistryMirrorVariants(t *testing.T) {
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("registries",
+			rdm("quay.io", "mirror.example.com", "registry.example.com:5000", "nested.example.com/quay"),
+			rdm("docker.io", "hub.example.com"),
+		),
+		newICSP("repositories", rdm("quay.io/ocp/release", "release.example.com/ocp/release", "flat.example.com/release")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	tests := []struct {
+		image    string
+		expected [][2]string
+	}{
+		{
+			image: "quay.io/ocp/release@" + testDigest,
+			expected: [][2]string{
+				{"quay.io/ocp/release@" + testDigest, ""},
+				{"mirror.example.com/ocp/release@" + testDigest, "mirror.example.com"},
+				{"registry.example.com:5000/ocp/release@" + testDigest, "registry.example.com:5000"},
+				{"nested.example.com/quay/ocp/release@" + testDigest, ""},
+				{"release.example.com/ocp/release@" + testDigest, ""},
+				{"flat.example.com/release@" + testDigest, ""},
+			},
+		},
+		{
+			image: "busybox:latest",
+			expected: [][2]string{
+				{"busybox:latest", ""},
+				{"hub.example.com/library/busybox:latest", "hub.example.com"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			alternates, err := s.OnFailureWithSources(context.Background(), mustParse(t, tt.image))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got [][2]string
+			for _, alternate := range alternates {
+				if len(alternate.RegistryRef.Namespace) > 0 || len(alternate.RegistryRef.Name) > 0 {
+					t.Errorf("expected the variant of %s to have no repository, got %#v", alternate.Ref.Exact(), alternate.RegistryRef)
+				}
+				got = append(got, [2]string{alternate.Ref.Exact(), alternate.RegistryRef.Registry})
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+// WebhookRequest is posted as JSON to a resolution webhook for every image with mirrors.
+type WebhookRequest struct {
+	// Image is the requested image, qualified with the registry it refers to.
+	Image string `json:"image"`
+	// Mirrors are the candidate mirrors of Image in the order they would be tried.
+	Mirrors []string `json:"mirrors"`
+}
+
+// WebhookResponse is the JSON answer of a resolution webhook.
+type WebhookResponse struct {
+	// Mirrors are the candidate mirrors to try, in order. Candidates that are left out are
+	// not tried, and every mirror listed must be one of the candidates.
+	Mirrors []string `json:"mirrors"`
+}
+
+// WithResolutionWebhook posts the candidate mirrors of each image to endpoint, which may
+// reorder or remove them to apply routing rules of its own. The webhook is called with
+// client, or with the client set by WithHTTPClient when client is nil, once per image, and
+// its answer is cached with the rest of the resolution. When the webhook fails or returns
+// mirrors that are not candidates, a warning is reported and the local order is used.
+func WithResolutionWebhook(endpoint string, client *http.Client) Option {
+	return func(s *OnErrorStrategy) {
+		s.webhookURL = endpoint
+		s.webhookClient = client
+	}
+}
+
+// routeMirrors replaces the mirrors of r with those returned by the resolution webhook,
+// and returns the warning to report if the webhook could not be used. It may be called
+// without holding s.lock.
+func (s *OnErrorStrategy) routeMirrors(ctx context.Context, r *resolution) string {
+	if len(s.webhookURL) == 0 || len(r.alternates) < 2 {
+		return ""
+	}
+	image := r.alternates[0].Ref
+	candidates := make(map[string]Alternate, len(r.alternates)-1)
+	req := WebhookRequest{Image: qualifiedRef(image).Exact()}
+	for _, alternate := range r.alternates[1:] {
+		candidates[alternate.Ref.Exact()] = alternate
+		req.Mirrors = append(req.Mirrors, alternate.Ref.Exact())
+	}
+	routed, err := s.callWebhook(ctx, req)
+	if err == nil {
+		for _, mirror := range routed {
+			if _, ok := candidates[mirror]; !ok {
+				err = fmt.Errorf("returned %s, which is not a mirror of the image", mirror)
+				break
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("resolution webhook %s failed for %s, the mirrors will be tried in their local order: %v", s.webhookURL, image.Exact(), err)
+	}
+
+	alternates := []Alternate{r.alternates[0]}
+	for _, mirror := range routed {
+		if alternate, ok := candidates[mirror]; ok {
+			alternates = append(alternates, alternate)
+			delete(candidates, mirror)
+		}
+	}
+	for _, alternate := range r.alternates[1:] {
+		if _, ok := candidates[alternate.Ref.Exact()]; ok {
+			klog.V(4).Infof("Skipping mirror %s of %s: %s", alternate.Ref.Exact(), alternate.Source, SkipWebhook)
+			r.skipped = append(r.skipped, Skipped{Ref: alternate.Ref, Policy: alternate.Policy, Source: alternate.Source, Reason: SkipWebhook})
+		}
+	}
+	r.alternates = alternates
+	return ""
+}
+
+// callWebhook posts req to the resolution webhook and returns the mirrors it answered with.
+func (s *OnErrorStrategy) callWebhook(ctx context.Context, req WebhookRequest) ([]string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	client := s.webhookClient
+	if client == nil {
+		client = s.httpClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("server returned %s", resp.Status)
+	}
+	data, err := readLimited(resp.Body, s.decode.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	var answer WebhookResponse
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	return answer.Mirrors, nil
+}
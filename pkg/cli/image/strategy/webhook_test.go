@@ -0,0 +1,102 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+)
+
+func TestWithResolutionWebhook(t *testing.T) {
+	var requests []WebhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req WebhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid webhook request: %v", err)
+		}
+		requests = append(requests, req)
+		var routed []string
+		switch r.URL.Path {
+		case "/route":
+			for i := len(req.Mirrors) - 1; i >= 0; i-- {
+				if !strings.HasPrefix(req.Mirrors[i], "drop.example.com/") {
+					routed = append(routed, req.Mirrors[i])
+				}
+			}
+		case "/unknown":
+			routed = []string{"unknown.example.com/ocp/release:4.8"}
+		case "/invalid":
+			w.Write([]byte("{"))
+			return
+		default:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(WebhookResponse{Mirrors: routed})
+	}))
+	defer server.Close()
+
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("release", rdm("quay.io/ocp/release", "a.example.com/ocp/release", "drop.example.com/ocp/release", "b.example.com/ocp/release")),
+	}}
+	local := []string{
+		"quay.io/ocp/release:4.8",
+		"a.example.com/ocp/release:4.8",
+		"drop.example.com/ocp/release:4.8",
+		"b.example.com/ocp/release:4.8",
+	}
+	tests := []struct {
+		path     string
+		expected []string
+		warning  string
+	}{
+		{
+			path:     "/route",
+			expected: []string{"quay.io/ocp/release:4.8", "b.example.com/ocp/release:4.8", "a.example.com/ocp/release:4.8"},
+		},
+		{path: "/unavailable", expected: local, warning: "server returned 503 Service Unavailable"},
+		{path: "/unknown", expected: local, warning: "returned unknown.example.com/ocp/release:4.8, which is not a mirror of the image"},
+		{path: "/invalid", expected: local, warning: "invalid response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			requests = nil
+			warnings := &bytes.Buffer{}
+			s := NewICSPOnErrorStrategy(client, "", WithResolutionWebhook(server.URL+tt.path, server.Client()), WithWarnings(warnings))
+			for i := 0; i < 2; i++ {
+				alternates, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/ocp/release:4.8"))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if got := exactRefs(alternates); !reflect.DeepEqual(got, tt.expected) {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+				}
+			}
+			expected := []WebhookRequest{{Image: "quay.io/ocp/release:4.8", Mirrors: local[1:]}}
+			if !reflect.DeepEqual(requests, expected) {
+				t.Errorf("expected the webhook to be called once with %v, got %v", expected, requests)
+			}
+			if len(tt.warning) == 0 && warnings.Len() > 0 {
+				t.Errorf("unexpected warnings: %s", warnings.String())
+			}
+			if !strings.Contains(warnings.String(), tt.warning) {
+				t.Errorf("expected a warning containing %q, got %q", tt.warning, warnings.String())
+			}
+		})
+	}
+
+	requests = nil
+	s := NewICSPOnErrorStrategy(client, "", WithResolutionWebhook(server.URL+"/route", server.Client()))
+	if _, err := s.OnFailure(context.Background(), mustParse(t, "quay.io/other/app:1.0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("expected the webhook not to be called for an image without mirrors, got %v", requests)
+	}
+}
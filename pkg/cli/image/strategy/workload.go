@@ -0,0 +1,95 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+
+	kappsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+)
+
+// ResolveObjectImages resolves the alternates of every container image referenced by obj,
+// which must be a Pod, PodTemplate or a workload with a pod template, in the namespace of
+// obj. The result is keyed by the path of the image field within obj, e.g.
+// spec.template.spec.containers[0].image.
+func (s *OnErrorStrategy) ResolveObjectImages(ctx context.Context, obj runtime.Object) (map[string][]reference.DockerImageReference, error) {
+	prefix, spec, err := podSpecFor(obj)
+	if err != nil {
+		return nil, err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	namespace := accessor.GetNamespace()
+	images := make(map[string][]reference.DockerImageReference)
+	resolveContainers := func(field string, containers []corev1.Container) error {
+		for i, container := range containers {
+			path := fmt.Sprintf("%s.%s[%d].image", prefix, field, i)
+			ref, err := s.parse(container.Image)
+			if err != nil {
+				return fmt.Errorf("%s: invalid image %q: %v", path, container.Image, err)
+			}
+			alternates, err := s.onFailure(ctx, namespace, ref)
+			if err != nil {
+				return fmt.Errorf("%s: %v", path, err)
+			}
+			images[path] = alternates
+		}
+		return nil
+	}
+	if err := resolveContainers("initContainers", spec.InitContainers); err != nil {
+		return nil, err
+	}
+	if err := resolveContainers("containers", spec.Containers); err != nil {
+		return nil, err
+	}
+	ephemeral := make([]corev1.Container, 0, len(spec.EphemeralContainers))
+	for _, container := range spec.EphemeralContainers {
+		ephemeral = append(ephemeral, corev1.Container{Image: container.Image})
+	}
+	if err := resolveContainers("ephemeralContainers", ephemeral); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// podSpecFor returns the pod spec of obj and the path of that spec within obj.
+func podSpecFor(obj runtime.Object) (string, *corev1.PodSpec, error) {
+	switch t := obj.(type) {
+	case *corev1.Pod:
+		return "spec", &t.Spec, nil
+	case *corev1.PodTemplate:
+		return "template.spec", &t.Template.Spec, nil
+	case *corev1.ReplicationController:
+		if t.Spec.Template == nil {
+			return "", nil, fmt.Errorf("replication controller %s has no pod template", t.Name)
+		}
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *appsv1.DeploymentConfig:
+		if t.Spec.Template == nil {
+			return "", nil, fmt.Errorf("deployment config %s has no pod template", t.Name)
+		}
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *kappsv1.Deployment:
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *kappsv1.StatefulSet:
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *kappsv1.DaemonSet:
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *kappsv1.ReplicaSet:
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *batchv1.Job:
+		return "spec.template.spec", &t.Spec.Template.Spec, nil
+	case *batchv1.CronJob:
+		return "spec.jobTemplate.spec.template.spec", &t.Spec.JobTemplate.Spec.Template.Spec, nil
+	default:
+		return "", nil, fmt.Errorf("objects of type %T do not reference container images", obj)
+	}
+}
@@ -0,0 +1,79 @@
+package strategy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	operatorv1alpha1 "github.com/openshift/api/operator/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const testPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+spec:
+  initContainers:
+  - name: setup
+    image: quay.io/ocp-test/tools:latest
+  containers:
+  - name: app
+    image: quay.io/ocp-test/app@sha256:0000000000000000000000000000000000000000000000000000000000000000
+  - name: sidecar
+    image: docker.io/library/busybox:latest
+`
+
+func TestResolveObjectImages(t *testing.T) {
+	var pod corev1.Pod
+	if err := yaml.Unmarshal([]byte(testPod), &pod); err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("test", rdm("quay.io/ocp-test", "registry.example.com/ocp-test")),
+	}}
+	s := NewICSPOnErrorStrategy(client, "")
+	images, err := s.ResolveObjectImages(context.Background(), &pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]string{
+		"spec.initContainers[0].image": {"quay.io/ocp-test/tools:latest", "registry.example.com/ocp-test/tools:latest"},
+		"spec.containers[0].image": {
+			"quay.io/ocp-test/app@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			"registry.example.com/ocp-test/app@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		"spec.containers[1].image": {"docker.io/library/busybox:latest"},
+	}
+	actual := make(map[string][]string, len(images))
+	for path, refs := range images {
+		actual[path] = exactRefs(refs)
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestResolveObjectImagesDeploymentConfig(t *testing.T) {
+	dc := &appsv1.DeploymentConfig{Spec: appsv1.DeploymentConfigSpec{Template: &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "quay.io/ocp-test/app:v1"}}},
+	}}}
+	client := &fakeICSPLister{items: []operatorv1alpha1.ImageContentSourcePolicy{
+		newICSP("test", rdm("quay.io/ocp-test", "registry.example.com/ocp-test")),
+	}}
+	images, err := NewICSPOnErrorStrategy(client, "").ResolveObjectImages(context.Background(), dc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	refs := images["spec.template.spec.containers[0].image"]
+	if len(refs) != 2 || refs[1].Exact() != "registry.example.com/ocp-test/app:v1" {
+		t.Errorf("unexpected alternates %v", refs)
+	}
+
+	if _, err := NewICSPOnErrorStrategy(client, "").ResolveObjectImages(context.Background(), &corev1.ConfigMap{}); err == nil {
+		t.Errorf("expected an error for an object without images")
+	}
+}